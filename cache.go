@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// DefaultCacheDir is where solc ASTs are cached unless --cache-dir is given
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gasoptimizer")
+}
+
+// astCache stores solc's JSON AST keyed by a hash of the source content,
+// the compiler that produced it and the tool version that decodes it
+type astCache struct {
+	dir      string
+	compiler string // compilerID of the solc in use
+}
+
+// path returns the cache file for source
+func (c astCache) path(source []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00", Version, c.compiler)
+	h.Write(source)
+	return filepath.Join(c.dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+// compilerIDs memoizes compilerID by solc path, size and modification time
+var compilerIDs sync.Map
+
+// compilerID identifies the solc on PATH by its resolved path and its
+// --version output, so a replaced or upgraded compiler misses the cache.
+// The output is only rerun when the binary changes.
+func compilerID(ctx context.Context) (string, error) {
+	path, err := exec.LookPath("solc")
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("%s\x00%d\x00%d", path, info.Size(), info.ModTime().UnixNano())
+	if id, ok := compilerIDs.Load(key); ok {
+		return id.(string), nil
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	id := path + "\x00" + string(out)
+	compilerIDs.Store(key, id)
+	return id, nil
+}
+
+// get returns the cached AST JSON for source, if any
+func (c astCache) get(source []byte) ([]byte, bool) {
+	if c.dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(source))
+	return data, err == nil
+}
+
+// put stores AST JSON for source. Failures only cost a future cache miss,
+// so they are ignored.
+func (c astCache) put(source, ast []byte) {
+	if c.dir == "" || os.MkdirAll(c.dir, 0o755) != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(c.dir, "ast-*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(ast)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if os.Rename(tmp.Name(), c.path(source)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestASTCacheKey(t *testing.T) {
+	dir := t.TempDir()
+	source, ast := []byte("contract C {}"), []byte(`{"nodeType":"SourceUnit"}`)
+	cache := astCache{dir: dir, compiler: "solc 0.8.24"}
+	if _, ok := cache.get(source); ok {
+		t.Fatal("hit on an empty cache")
+	}
+	cache.put(source, ast)
+	if got, ok := cache.get(source); !ok || string(got) != string(ast) {
+		t.Fatalf("get = %q, %v; want the stored AST", got, ok)
+	}
+
+	if _, ok := cache.get([]byte("contract D {}")); ok {
+		t.Error("hit for other source")
+	}
+	if _, ok := (astCache{dir: dir, compiler: "solc 0.8.26"}).get(source); ok {
+		t.Error("hit for another compiler")
+	}
+	defer func(v string) { Version = v }(Version)
+	Version = "v9.9.9"
+	if _, ok := cache.get(source); ok {
+		t.Error("hit for another tool version")
+	}
+	if _, ok := (astCache{}).get(source); ok {
+		t.Error("hit with caching disabled")
+	}
+}
+
+// fakeSolc installs a solc on PATH that reports version and counts its
+// compilations in the returned file
+func fakeSolc(t *testing.T, bin, version string) (runs string) {
+	t.Helper()
+	runs = filepath.Join(bin, "runs")
+	script := `#!/bin/sh
+if [ "$1" = "--version" ]; then
+  echo "solc, the solidity compiler commandline interface"
+  echo "Version: ` + version + `"
+  exit 0
+fi
+echo run >> "` + runs + `"
+echo "JSON AST (compact format):"
+echo '{"nodeType":"SourceUnit","src":"0:13:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:13:0"}]}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "solc"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return runs
+}
+
+func TestASTCacheHitMiss(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake solc is a shell script")
+	}
+	bin, dir := t.TempDir(), t.TempDir()
+	t.Setenv("PATH", bin)
+	file := filepath.Join(t.TempDir(), "C.sol")
+	if err := os.WriteFile(file, []byte("contract C {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{CacheDir: dir, Logger: DiscardLogger}
+	compilations := func(runs string) int {
+		data, _ := os.ReadFile(runs)
+		return strings.Count(string(data), "run")
+	}
+	analyze := func() {
+		t.Helper()
+		g, err := NewGasOptimizerOptions(context.Background(), file, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := g.AST.(*SolcASTNode); !ok {
+			t.Fatalf("AST is %T, want the solc AST", g.AST)
+		}
+	}
+
+	runs := fakeSolc(t, bin, "0.8.24+commit.e11b9ed9")
+	analyze()
+	analyze()
+	if n := compilations(runs); n != 1 {
+		t.Errorf("solc ran %d times for two analyses of the same file, want 1", n)
+	}
+
+	os.Remove(runs)
+	runs = fakeSolc(t, bin, "0.8.26+commit.8a97fa7a.Linux.g++")
+	analyze()
+	analyze()
+	if n := compilations(runs); n != 1 {
+		t.Errorf("after a solc upgrade solc ran %d times, want 1", n)
+	}
+
+	if err := os.WriteFile(file, []byte("contract C { }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	analyze()
+	if n := compilations(runs); n != 2 {
+		t.Errorf("after a source change solc ran %d times in total, want 2", n)
+	}
+}
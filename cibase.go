@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// dropBaseFindings removes from results every finding that the same files
+// already had where HEAD branched off ref, leaving only the findings the
+// current branch introduced. The base is analyzed with the same config in a
+// temporary worktree; files missing there are new, so all their findings
+// stay. A finding matches a base finding with the same fingerprint (rule,
+// file, enclosing function and issue text), so moved code does not count as
+// new but a changed count does.
+func dropBaseFindings(ctx context.Context, results []*GasOptimizer, ref string, cfg *Config, opts Options, jobs int) error {
+	if len(results) == 0 {
+		return nil
+	}
+	root, err := gitRoot(results[0].Path)
+	if err != nil {
+		return err
+	}
+	base, err := mergeBase(root, ref)
+	if err != nil {
+		return err
+	}
+	worktree, _, cleanup, err := gitWorktree(root, base)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	current := make([]string, len(results))
+	for i, g := range results {
+		current[i] = g.Path
+	}
+	mapped, err := worktreePaths(current, root, worktree)
+	if err != nil {
+		return err
+	}
+	origin := make(map[string]string) // base file -> current file
+	var files []string
+	for i, path := range mapped {
+		if _, err := os.Stat(path); err == nil {
+			files = append(files, path)
+			origin[path] = current[i]
+		}
+	}
+	opts.MaxReports = 0
+	baseResults, err := AnalyzeFiles(ctx, files, cfg, opts, jobs)
+	if err != nil {
+		return fmt.Errorf("analyzing %s: %v", ref, err)
+	}
+
+	seen := make(map[string]int)
+	for _, g := range baseResults {
+		for _, r := range g.Reports {
+			seen[findingKey(g, origin[g.Path], r)]++
+		}
+	}
+	for _, g := range results {
+		kept := g.Reports[:0]
+		for _, r := range g.Reports {
+			if key := findingKey(g, g.Path, r); seen[key] > 0 {
+				seen[key]--
+				continue
+			}
+			kept = append(kept, r)
+		}
+		g.Reports = kept
+		g.scoreMetrics()
+	}
+	return nil
+}
+
+// findingKey identifies a finding of g independently of its line: its
+// fingerprint with the analyzed file named path. Findings in flattened
+// files keep their original file name.
+func findingKey(g *GasOptimizer, path string, r Report) string {
+	file := r.file
+	if file == g.Path {
+		file = path
+	}
+	return r.fingerprint(file)
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+)
+
+// Version is the tool version, set at build time with
+// -ldflags "-X main.Version=v1.2.3"
+var Version = "dev"
+
+// Exit codes
+const (
+	ExitOK       = 0 // no error-level findings
+	ExitFindings = 1 // error-level findings present
+	ExitUsage    = 2 // bad invocation or failed analysis
+
+	ExitInterrupted = 130 // cancelled with Ctrl-C, partial results printed
+)
+
+// command is a CLI subcommand
+type command struct {
+	Name    string
+	Summary string
+	Run     func(args []string) int
+}
+
+// commands lists the subcommands; filled in init to allow usage() to refer to it
+var commands []command
+
+func init() {
+	commands = []command{
+		{"analyze", "analyze a Solidity or Yul file, or a directory", runAnalyze},
+		{"doctor", "check solc availability and the fallback parser", runDoctor},
+		{"serve", "answer JSON-RPC analyze requests on stdin for editor integration", runServe},
+		{"http", "serve POST /analyze over HTTP for web integrations", runHTTP},
+		{"rules", "list every rule with its default severity and confidence", runRules},
+		{"explain", "print detailed guidance, with an example, for one rule", runExplain},
+		{"version", "print the version", runVersion},
+	}
+}
+
+// run dispatches to a subcommand and returns the process exit code
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return ExitUsage
+	}
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+		return ExitOK
+	}
+	for _, cmd := range commands {
+		if cmd.Name == args[0] {
+			return cmd.Run(args[1:])
+		}
+	}
+	// Keep the original "gasoptimizer <file.sol>" form working
+	if strings.HasSuffix(args[0], ".sol") || isYulFile(args[0]) {
+		return runAnalyze(args)
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return ExitUsage
+}
+
+// usage prints the top-level help
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gasoptimizer <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.Name, cmd.Summary)
+	}
+	fmt.Fprintln(w, "\nRun 'gasoptimizer <command> -h' for command flags.")
+}
+
+// parseInterspersed parses flags that may appear before or after positional
+// arguments, returning the positionals in order
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// runAnalyze implements "gasoptimizer analyze <path> [flags]"
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to JSON config (default "+DefaultConfigFile+" if present)")
+	enable := fs.String("enable", "", "comma-separated rule IDs or globs to enable (all, none)")
+	disable := fs.String("disable", "", "comma-separated rule IDs or globs to disable (all, none)")
+	includeOnly := fs.String("include-only", "", "comma-separated rule IDs or globs: run only these detectors, skipping the rest")
+	minConfidence := fs.String("min-confidence", "", "drop findings below this confidence: low, medium, high")
+	metrics := fs.Bool("metrics", false, "print per-contract metrics after the reports")
+	gasReportPath := fs.String("gas-report", "", "correlate estimated savings with a Foundry gas report (forge test --gas-report --json)")
+	since := fs.String("since", "", "only report findings on lines changed since this git ref")
+	ciBase := fs.String("ci-base", "", "only report findings this branch introduces: compare with the analysis at its merge base with this ref")
+	gitRef := fs.String("git-ref", "", "analyze the paths as of this git ref, checked out into a temporary worktree")
+	format := fs.String("format", "text", "stdout format: "+formatNames())
+	sortMode := fs.String("sort", DefaultSort, "order findings by "+sortNames()+" (roi: savings per unit of effort, best first)")
+	collapse := fs.Bool("collapse", false, "in text output, show the first few findings of each rule and count the rest")
+	cacheDir := fs.String("cache-dir", DefaultCacheDir(), "directory caching solc ASTs by source hash")
+	contextLines := fs.Int("context", 0, "lines of source shown around each finding's snippet")
+	noCache := fs.Bool("no-cache", false, "always run solc, ignoring and not updating the AST cache")
+	noSolc := fs.Bool("no-solc", false, "never run solc: parse with the built-in parser, which only runs a narrow loop storage read check (GAS001)")
+	verbose := fs.Bool("verbose", false, "log debug messages such as AST cache hits")
+	loopIterations := fs.Int("assume-loop-iterations", 0, fmt.Sprintf("trip count assumed for loops without a literal bound when estimating savings (default %d, or loopIterations from the config)", DefaultLoopIterations))
+	fromEtherscan := fs.String("from-etherscan", "", "analyze the verified source of the contract at this address instead of a local path")
+	apiKey := fs.String("api-key", os.Getenv("ETHERSCAN_API_KEY"), "Etherscan API key for --from-etherscan (default $ETHERSCAN_API_KEY)")
+	chainID := fs.Int("chain-id", 1, "chain of the --from-etherscan contract")
+	maxReports := fs.Int("max-reports", 0, "show only the first this many findings in the --sort order and report how many were suppressed (0 = no limit)")
+	failOn := fs.String("fail-on", "", "exit 1 when a finding matches this predicate, e.g. 'severity>=high,confidence>=medium' (default: any error-level finding)")
+	maxTotalSavings := fs.Int("max-total-savings", -1, "exit 1 only when the total estimated savings of all findings exceed this gas budget (negative = no budget)")
+	jobs := fs.Int("jobs", runtime.NumCPU(), "files analyzed in parallel when given a directory")
+	var reports reportTargets
+	fs.Var(&reports, "report", "also write `format:path` (repeatable), e.g. sarif:results.sarif")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gasoptimizer analyze <file.sol|file.yul|directory>... [flags]")
+		fmt.Fprintln(fs.Output(), "       gasoptimizer analyze --from-etherscan <address> --api-key <key> [flags]")
+		fs.PrintDefaults()
+	}
+	paths, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		return ExitOK
+	}
+	if err != nil {
+		return ExitUsage
+	}
+	if (len(paths) == 0) == (*fromEtherscan == "") {
+		fs.Usage()
+		return ExitUsage
+	}
+	writeStdout, ok := formats[*format]
+	if !ok {
+		log.Printf("Error: unknown format %q (want %s)", *format, formatNames())
+		return ExitUsage
+	}
+	sortReports, ok := sortModes[*sortMode]
+	if !ok {
+		log.Printf("Error: unknown sort %q (want %s)", *sortMode, sortNames())
+		return ExitUsage
+	}
+	if *collapse && *format == "text" {
+		writeStdout = (*GasOptimizer).WriteCollapsedText
+	}
+
+	cfg, err := loadConfigFlag(*configPath)
+	if err == nil {
+		cfg, err = cfg.ApplyRuleFlags(*enable, *disable)
+	}
+	if err == nil && *includeOnly != "" {
+		cfg, err = cfg.ApplyIncludeOnly(*includeOnly)
+	}
+	if err == nil && *minConfidence != "" {
+		if cfg.MinConfidence = Confidence(*minConfidence); !validConfidence(cfg.MinConfidence) {
+			err = fmt.Errorf("invalid --min-confidence %q (want low, medium or high)", *minConfidence)
+		}
+	}
+	if err == nil && *loopIterations != 0 {
+		if cfg.LoopIterations = *loopIterations; *loopIterations < 0 {
+			err = fmt.Errorf("invalid --assume-loop-iterations %d (want a positive count)", *loopIterations)
+		}
+	}
+	var failPred failPredicate
+	if err == nil && *failOn != "" {
+		failPred, err = parseFailOn(*failOn)
+	}
+	if err == nil && *maxTotalSavings >= 0 && *maxReports > 0 {
+		err = fmt.Errorf("--max-total-savings cannot be combined with --max-reports, which drops findings from the total")
+	}
+	var gasReport ForgeGasReport
+	if err == nil && *gasReportPath != "" {
+		gasReport, err = LoadForgeGasReport(*gasReportPath)
+	}
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+
+	if *verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// --max-reports applies after sorting, so every finding is collected
+	opts := Options{CacheDir: *cacheDir, Context: *contextLines, NoSolc: *noSolc}
+	if *noCache {
+		opts.CacheDir = ""
+	}
+	if *fromEtherscan != "" {
+		dir, err := fetchEtherscanSource(ctx, *fromEtherscan, *apiKey, *chainID)
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return ExitUsage
+		}
+		defer os.RemoveAll(dir)
+		paths = []string{dir}
+	}
+	inputs := paths
+	var worktree string
+	if *ciBase != "" && (*gitRef != "" || *fromEtherscan != "") {
+		log.Printf("Error: --ci-base cannot be combined with --git-ref or --from-etherscan")
+		return ExitUsage
+	}
+	if *gitRef != "" {
+		if *fromEtherscan != "" {
+			log.Printf("Error: --git-ref cannot be combined with --from-etherscan")
+			return ExitUsage
+		}
+		var root string
+		var cleanup func()
+		worktree, root, cleanup, err = gitWorktree(paths[0], *gitRef)
+		if err == nil {
+			defer cleanup()
+			inputs, err = worktreePaths(paths, root, worktree)
+		}
+		if err == nil && *since != "" {
+			*since, err = resolveCommit(root, *since)
+		}
+		if err != nil {
+			log.Printf("Error: %v", err)
+			return ExitUsage
+		}
+	}
+	files, err := expandPaths(inputs)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	results, err := AnalyzeFiles(ctx, files, cfg, opts, *jobs)
+	interrupted := errors.Is(err, context.Canceled)
+	if err != nil && !interrupted {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	if interrupted {
+		log.Printf("Interrupted, showing results for %d completed files", len(results))
+	}
+	if *since != "" {
+		for _, g := range results {
+			ranges, err := changedLines(g.Path, *since)
+			if err != nil {
+				log.Printf("Error: %v", err)
+				return ExitUsage
+			}
+			g.FilterChangedLines(ranges)
+		}
+	}
+	if *ciBase != "" && !interrupted {
+		if err := dropBaseFindings(ctx, results, *ciBase, cfg, opts, *jobs); err != nil {
+			log.Printf("Error: --ci-base: %v", err)
+			return ExitUsage
+		}
+	}
+	if *fromEtherscan != "" {
+		trimResultPaths(results, paths[0])
+		paths[0] = *fromEtherscan
+	}
+	if worktree != "" {
+		// Report paths relative to the repository root
+		trimResultPaths(results, worktree)
+	}
+	optimizer := mergeResults(strings.Join(paths, " "), results)
+	sortReports(optimizer.Reports)
+	optimizer.limitReports(*maxReports)
+	if gasReport != nil {
+		optimizer.GasReport = optimizer.CorrelateGasReport(gasReport)
+	}
+	if err := writeStdout(optimizer, os.Stdout); err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	if *metrics && *format == "text" {
+		optimizer.PrintMetrics()
+	}
+	if gasReport != nil && *format == "text" {
+		optimizer.PrintGasCorrelation()
+	}
+	if optimizer.Suppressed > 0 {
+		log.Printf("Note: %d more findings suppressed by --max-reports %d", optimizer.Suppressed, *maxReports)
+	}
+	for _, target := range reports {
+		if err := optimizer.writeReportFile(target); err != nil {
+			log.Printf("Error: writing %s report: %v", target.Format, err)
+			return ExitUsage
+		}
+	}
+	if interrupted {
+		return ExitInterrupted
+	}
+	overBudget := false
+	if *maxTotalSavings >= 0 {
+		if total := optimizer.Summarize().GasSavings; total > *maxTotalSavings {
+			log.Printf("Total estimated savings of %d gas exceed the --max-total-savings budget of %d", total, *maxTotalSavings)
+			overBudget = true
+		}
+	}
+	if failPred != nil {
+		if overBudget || failPred.anyMatch(optimizer.Reports) {
+			return ExitFindings
+		}
+		return ExitOK
+	}
+	if *maxTotalSavings >= 0 {
+		// The budget replaces the error-level policy
+		if overBudget {
+			return ExitFindings
+		}
+		return ExitOK
+	}
+	if optimizer.HasErrors() {
+		return ExitFindings
+	}
+	return ExitOK
+}
+
+// loadConfigFlag loads the --config file, falling back to DefaultConfigFile
+// in the working directory; no config at all yields nil
+func loadConfigFlag(path string) (*Config, error) {
+	if path == "" {
+		if _, err := os.Stat(DefaultConfigFile); err != nil {
+			return nil, nil
+		}
+		path = DefaultConfigFile
+	}
+	return LoadConfig(path)
+}
+
+// runVersion implements "gasoptimizer version"
+func runVersion(args []string) int {
+	fmt.Println("gasoptimizer", Version)
+	return ExitOK
+}
+
+// runRules implements "gasoptimizer rules [--format table|json]"
+func runRules(args []string) int {
+	fs := flag.NewFlagSet("rules", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(Rules); err != nil {
+			log.Printf("Error: %v", err)
+			return ExitUsage
+		}
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tCATEGORY\tAREA\tSEVERITY\tCONFIDENCE\tEFFORT\tDEFAULT\tDESCRIPTION")
+		for _, r := range Rules {
+			level := LevelWarn
+			if r.Disabled {
+				level = LevelOff
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Name, r.Category, r.Area, r.Severity, r.Confidence, r.Effort, level, r.Description)
+		}
+		tw.Flush()
+	default:
+		log.Printf("Error: unknown format %q (want table or json)", *format)
+		return ExitUsage
+	}
+	return ExitOK
+}
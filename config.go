@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// DefaultConfigFile is loaded from the working directory when --config is not given
+const DefaultConfigFile = ".gasoptimizer.json"
+
+// DefaultLoopIterations is the trip count assumed for loops without a
+// literal bound when estimating per-iteration savings
+const DefaultLoopIterations = 10
+
+// Config holds user settings loaded from a JSON file
+type Config struct {
+	Rules         map[string]Level `json:"rules"`                   // rule ID -> error/warn/off
+	MinConfidence Confidence       `json:"minConfidence,omitempty"` // drop findings below this confidence
+
+	// ExemptVariables are storage variables never suggested for caching,
+	// e.g. values that must be re-read for reentrancy or oracle freshness
+	ExemptVariables []string `json:"exemptVariables,omitempty"`
+
+	// LoopIterations is the trip count assumed for loops whose bound is
+	// not a literal (default DefaultLoopIterations)
+	LoopIterations int `json:"loopIterations,omitempty"`
+
+	// GasCosts overrides opcode costs of the gas model by name, e.g.
+	// {"SLOAD_COLD": 800} for a chain that reprices storage
+	GasCosts map[string]int `json:"gasCosts,omitempty"`
+
+	// only, when set by --include-only, lists the rules whose detectors run
+	only map[string]bool
+}
+
+// LoadConfig reads and validates a JSON config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %v", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	for id, level := range cfg.Rules {
+		if _, ok := findRule(id); !ok {
+			return nil, fmt.Errorf("config %s: unknown rule %q", path, id)
+		}
+		if !validLevel(level) {
+			return nil, fmt.Errorf("config %s: rule %s has invalid level %q (want error, warn or off)", path, id, level)
+		}
+	}
+	if cfg.MinConfidence != "" && !validConfidence(cfg.MinConfidence) {
+		return nil, fmt.Errorf("config %s: invalid minConfidence %q (want low, medium or high)", path, cfg.MinConfidence)
+	}
+	if cfg.LoopIterations < 0 {
+		return nil, fmt.Errorf("config %s: loopIterations must be positive, got %d", path, cfg.LoopIterations)
+	}
+	if err := validateGasCosts(cfg.GasCosts); err != nil {
+		return nil, fmt.Errorf("config %s: %v", path, err)
+	}
+	return &cfg, nil
+}
+
+// LevelFor returns the configured level for a rule, defaulting to warn, or
+// off for rules disabled by default
+func (c *Config) LevelFor(ruleID string) Level {
+	if c != nil {
+		if level, ok := c.Rules[ruleID]; ok {
+			return level
+		}
+	}
+	if rule, ok := findRule(ruleID); ok && rule.Disabled {
+		return LevelOff
+	}
+	return LevelWarn
+}
+
+// isExempt reports whether a read such as data[i] or s.x is of an exempt
+// variable, matching either the whole expression or its base name
+func (c *Config) isExempt(expr string) bool {
+	if c == nil {
+		return false
+	}
+	base := expr
+	if i := strings.IndexAny(expr, "[."); i >= 0 {
+		base = expr[:i]
+	}
+	for _, name := range c.ExemptVariables {
+		if name == expr || name == base {
+			return true
+		}
+	}
+	return false
+}
+
+// loopIterations returns the assumed trip count of loops without a literal bound
+func (c *Config) loopIterations() int {
+	if c == nil || c.LoopIterations <= 0 {
+		return DefaultLoopIterations
+	}
+	return c.LoopIterations
+}
+
+// meetsConfidence reports whether a finding of confidence c passes the
+// configured minimum
+func (c *Config) meetsConfidence(conf Confidence) bool {
+	if c == nil || c.MinConfidence == "" {
+		return true
+	}
+	return confidenceRank[conf] >= confidenceRank[c.MinConfidence]
+}
+
+// ApplyRuleFlags layers --enable/--disable selections over the config.
+// Each list is comma-separated rule IDs or globs (GAS00*); "all" matches
+// every rule and "none" selects the opposite of all. Disables apply first,
+// so "--disable all --enable GAS001" runs a single rule. Enabling a rule
+// keeps a configured error level and otherwise sets it to warn.
+func (c *Config) ApplyRuleFlags(enable, disable string) (*Config, error) {
+	merged := &Config{}
+	if c != nil {
+		*merged = *c
+	}
+	merged.Rules = make(map[string]Level)
+	if c != nil {
+		for id, level := range c.Rules {
+			merged.Rules[id] = level
+		}
+	}
+	disabled, enableAll, err := matchRules(disable)
+	if err != nil {
+		return nil, fmt.Errorf("--disable: %v", err)
+	}
+	enabled, disableAll, err := matchRules(enable)
+	if err != nil {
+		return nil, fmt.Errorf("--enable: %v", err)
+	}
+	if enableAll {
+		enabled = append(enabled, allRuleIDs()...)
+	}
+	if disableAll {
+		disabled = append(disabled, allRuleIDs()...)
+	}
+	for _, id := range disabled {
+		merged.Rules[id] = LevelOff
+	}
+	for _, id := range enabled {
+		if merged.Rules[id] != LevelError {
+			merged.Rules[id] = LevelWarn
+		}
+	}
+	return merged, nil
+}
+
+// ApplyIncludeOnly restricts analysis to the listed rule IDs or globs:
+// every other rule is turned off, and detectors reporting none of the
+// remaining rules are skipped rather than run and filtered. Listed rules
+// keep a level set by the config or --enable/--disable, including off, and
+// are otherwise set to warn, even if off by default.
+func (c *Config) ApplyIncludeOnly(list string) (*Config, error) {
+	ids, none, err := matchRules(list)
+	if err != nil {
+		return nil, fmt.Errorf("--include-only: %v", err)
+	}
+	if len(ids) == 0 && !none {
+		return nil, fmt.Errorf("--include-only: no rules listed")
+	}
+	merged := &Config{}
+	if c != nil {
+		*merged = *c
+	}
+	merged.Rules = make(map[string]Level)
+	merged.only = make(map[string]bool)
+	for _, id := range allRuleIDs() {
+		merged.Rules[id] = LevelOff
+	}
+	for _, id := range ids {
+		level := LevelWarn
+		if c != nil {
+			if configured, ok := c.Rules[id]; ok {
+				level = configured
+			}
+		}
+		merged.Rules[id] = level
+		merged.only[id] = level != LevelOff
+	}
+	return merged, nil
+}
+
+// runs reports whether a detector reporting any of the given rules should
+// run, which is always unless --include-only excludes all of them
+func (c *Config) runs(ids ...string) bool {
+	if c == nil || c.only == nil {
+		return true
+	}
+	for _, id := range ids {
+		if c.only[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// reports reports whether findings of rule id are kept: its detector
+// runs, the rule is not off and it meets the minimum confidence. Detectors
+// leaving a finding to another rule check this first, so that turning the
+// other rule off does not lose the finding.
+func (c *Config) reports(id string) bool {
+	rule, ok := findRule(id)
+	return ok && c.runs(id) && c.LevelFor(id) != LevelOff && c.meetsConfidence(rule.Confidence)
+}
+
+// matchRules expands a comma-separated list of rule IDs and globs. none
+// reports whether the list was the "none" keyword.
+func matchRules(list string) (ids []string, none bool, err error) {
+	for _, pattern := range strings.Split(list, ",") {
+		pattern = strings.ToUpper(strings.TrimSpace(pattern))
+		switch pattern {
+		case "":
+			continue
+		case "ALL":
+			pattern = "*"
+		case "NONE":
+			none = true
+			continue
+		}
+		matched := false
+		for _, r := range Rules {
+			if ok, err := path.Match(pattern, r.ID); err != nil {
+				return nil, false, fmt.Errorf("bad pattern %q: %v", pattern, err)
+			} else if ok {
+				ids = append(ids, r.ID)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, false, fmt.Errorf("no rule matches %q", pattern)
+		}
+	}
+	return ids, none, nil
+}
+
+// allRuleIDs lists every rule ID
+func allRuleIDs() []string {
+	ids := make([]string, len(Rules))
+	for i, r := range Rules {
+		ids[i] = r.ID
+	}
+	return ids
+}
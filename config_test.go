@@ -0,0 +1,78 @@
+package main
+
+import "testing"
+
+func TestApplyIncludeOnly(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          *Config
+		enable, disable string
+		include         string
+		want            map[string]Level
+		runs, skipped   []string
+	}{
+		{
+			name:    "others off",
+			include: RuleLoopStorageRead,
+			want:    map[string]Level{RuleLoopStorageRead: LevelWarn, RuleInefficientType: LevelOff},
+			runs:    []string{RuleLoopStorageRead},
+			skipped: []string{RuleInefficientType},
+		},
+		{
+			name:    "disabled by flag",
+			disable: RuleLoopStorageRead,
+			include: RuleLoopStorageRead + "," + RuleInefficientType,
+			want:    map[string]Level{RuleLoopStorageRead: LevelOff, RuleInefficientType: LevelWarn},
+			runs:    []string{RuleInefficientType},
+			skipped: []string{RuleLoopStorageRead},
+		},
+		{
+			name:    "off and error in config",
+			config:  &Config{Rules: map[string]Level{RuleLoopStorageRead: LevelOff, RuleInefficientType: LevelError}},
+			include: "GAS00*",
+			want:    map[string]Level{RuleLoopStorageRead: LevelOff, RuleInefficientType: LevelError, RuleRedundantExpression: LevelWarn, RuleInlinedModifier: LevelOff},
+			runs:    []string{RuleInefficientType, RuleRedundantExpression},
+			skipped: []string{RuleLoopStorageRead, RuleInlinedModifier},
+		},
+		{
+			name:    "off by default",
+			include: RuleMissingZeroCheck,
+			want:    map[string]Level{RuleMissingZeroCheck: LevelWarn},
+			runs:    []string{RuleMissingZeroCheck},
+		},
+		{
+			name:    "enabled by flag",
+			config:  &Config{Rules: map[string]Level{RuleLoopStorageRead: LevelOff}},
+			enable:  RuleLoopStorageRead,
+			include: RuleLoopStorageRead,
+			want:    map[string]Level{RuleLoopStorageRead: LevelWarn},
+			runs:    []string{RuleLoopStorageRead},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.config.ApplyRuleFlags(tt.enable, tt.disable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg, err = cfg.ApplyIncludeOnly(tt.include); err != nil {
+				t.Fatal(err)
+			}
+			for id, want := range tt.want {
+				if got := cfg.LevelFor(id); got != want {
+					t.Errorf("%s level = %s, want %s", id, got, want)
+				}
+			}
+			for _, id := range tt.runs {
+				if !cfg.runs(id) {
+					t.Errorf("detectors of %s skipped, want run", id)
+				}
+			}
+			for _, id := range tt.skipped {
+				if cfg.runs(id) {
+					t.Errorf("detectors of %s run, want skipped", id)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,3577 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// checkRequireStrings detects require calls with a string message that
+// could revert with a custom error instead. The suggested form follows the
+// pragma: require(cond, CustomError()) needs 0.8.26, custom errors 0.8.4;
+// below that only messages longer than a word are reported, to be
+// shortened. Without a pragma the 0.8.4 form is suggested.
+func (g *GasOptimizer) checkRequireStrings(ast *SolcASTNode) {
+	min, ok := g.pragmaMinVersion(ast)
+	customErrors := !ok || min.atLeast(solcVersion{0, 8, 4})
+	suggestion := "Replace the string with a custom error: if (!cond) revert CustomError();"
+	if ok && min.atLeast(solcVersion{0, 8, 26}) {
+		suggestion = "Replace the string with a custom error: require(cond, CustomError())"
+	}
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "FunctionCall" || node.Expression == nil || node.Expression.Name != "require" {
+			return
+		}
+		if len(node.Arguments) < 2 || node.Arguments[1].NodeType != "Literal" || node.Arguments[1].Kind != "string" {
+			return
+		}
+		message := node.Arguments[1].Value
+		words := (len(message) + 31) / 32
+		if words == 0 {
+			words = 1
+		}
+		report := Report{
+			RuleID:     RuleRequireString,
+			Issue:      fmt.Sprintf("require uses a %d-byte revert string \"%s\"", len(message), message),
+			Suggestion: suggestion,
+			GasSavings: words * GasRevertStringWord,
+			Location:   node.Src,
+		}
+		if !customErrors {
+			if words == 1 {
+				return
+			}
+			report.Suggestion = "Shorten the message to 32 bytes or fewer; custom errors need Solidity 0.8.4"
+			report.GasSavings = (words - 1) * GasRevertStringWord
+		}
+		g.addReport(report)
+	})
+}
+
+// checkConstantConditions detects if/require conditions that are boolean
+// literals, leaving a dead branch or a check that can never fail
+func (g *GasOptimizer) checkConstantConditions(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		switch {
+		case node.NodeType == "IfStatement" && isBoolLiteral(node.Condition):
+			dead := "else branch"
+			if node.Condition.Value == "false" {
+				dead = "if body"
+			}
+			if node.Condition.Value == "true" && node.FalseBody == nil {
+				dead = "condition check"
+			}
+			g.addReport(Report{
+				RuleID:     RuleConstantCondition,
+				Issue:      fmt.Sprintf("if condition is always %s; the %s is dead code", node.Condition.Value, dead),
+				Suggestion: "Remove the constant condition and the unreachable branch",
+				GasSavings: GasConditionCheck,
+				Location:   node.Src,
+			})
+		case node.NodeType == "FunctionCall" && node.Expression != nil && node.Expression.Name == "require" &&
+			len(node.Arguments) > 0 && isBoolLiteral(&node.Arguments[0]) && node.Arguments[0].Value == "true":
+			g.addReport(Report{
+				RuleID:     RuleConstantCondition,
+				Issue:      "require(true) can never fail",
+				Suggestion: "Remove the redundant require",
+				GasSavings: GasConditionCheck,
+				Location:   node.Src,
+			})
+		}
+	})
+}
+
+// isBoolLiteral reports whether node is a true/false literal
+func isBoolLiteral(node *SolcASTNode) bool {
+	return node != nil && node.NodeType == "Literal" && node.Kind == "bool"
+}
+
+// checkLoopAllocations detects memory variables allocated afresh on every
+// loop iteration from a loop-invariant initializer. Only initializers that
+// allocate count: new, array and struct literals, abi.encode* and copies
+// out of storage, not pointer copies. Calls to functions that are not pure
+// may return something else each time, and storage copies vary when the
+// loop may write storage through a call.
+func (g *GasOptimizer) checkLoopAllocations(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if !isLoop(node) || node.Body == nil {
+			return
+		}
+		written := g.writtenDecls(node)
+		declared := g.declaredDecls(node)
+		writesStorage := false
+		g.walkSolcAST(node, func(n *SolcASTNode) {
+			writesStorage = writesStorage || (isFunctionCall(n) && !callMutability(n, "pure", "view"))
+		})
+		g.inspectSolcAST(node.Body, func(stmt *SolcASTNode) bool {
+			if isLoop(stmt) {
+				return false // nested loops are checked on their own
+			}
+			if stmt.NodeType != "VariableDeclarationStatement" || stmt.InitialValue == nil || len(stmt.Declarations) != 1 {
+				return true
+			}
+			decl := stmt.Declarations[0]
+			if decl.StorageLocation != "memory" || written[decl.ID] {
+				return true // mutated per iteration, e.g. an accumulator
+			}
+			allocates, readsStorage := allocation(unparen(stmt.InitialValue))
+			if !allocates || (readsStorage && writesStorage) {
+				return true
+			}
+			for _, ref := range g.referencedDecls(stmt.InitialValue) {
+				if written[ref] || declared[ref] {
+					return true // initializer depends on the iteration
+				}
+			}
+			varying := false
+			g.walkSolcAST(stmt.InitialValue, func(n *SolcASTNode) {
+				varying = varying || (isFunctionCall(n) && n.Expression.NodeType != "NewExpression" && !callMutability(n, "pure"))
+			})
+			if varying {
+				return true
+			}
+			savings, note := g.loopSavings(node, fixedSavings(GasMemoryAlloc))
+			g.addReport(Report{
+				RuleID:        RuleLoopAllocation,
+				Issue:         fmt.Sprintf("Memory variable '%s' is reallocated on every loop iteration%s", decl.Name, note),
+				Suggestion:    fmt.Sprintf("Declare '%s' once above the loop", decl.Name),
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      stmt.Src,
+			})
+			return true
+		})
+	})
+}
+
+// isLoop reports whether node is a for, while or do-while loop
+func isLoop(node *SolcASTNode) bool {
+	return node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" || node.NodeType == "DoWhileStatement"
+}
+
+// isFunctionCall reports whether node calls a function, as opposed to a
+// type conversion or struct constructor
+func isFunctionCall(node *SolcASTNode) bool {
+	return node.NodeType == "FunctionCall" && node.Kind == "functionCall" && node.Expression != nil
+}
+
+// callMutability reports whether the function call's type, e.g.
+// "function (uint256) view returns (uint256)", has one of the given state
+// mutabilities
+func callMutability(call *SolcASTNode, mutabilities ...string) bool {
+	if call.Expression.TypeDescriptions == nil {
+		return false
+	}
+	head, _, _ := strings.Cut(call.Expression.TypeDescriptions.TypeString, " returns ")
+	for _, m := range mutabilities {
+		if strings.HasSuffix(head, " "+m) {
+			return true
+		}
+	}
+	return false
+}
+
+// allocation reports whether evaluating an initializer allocates memory,
+// and whether that is a copy out of storage
+func allocation(init *SolcASTNode) (allocates, fromStorage bool) {
+	switch {
+	case init.NodeType == "FunctionCall" && init.Kind == "structConstructorCall":
+		return true, false
+	case init.NodeType == "TupleExpression" && init.IsInlineArray:
+		return true, false
+	case isFunctionCall(init) && init.Expression.NodeType == "NewExpression":
+		return true, false
+	case isFunctionCall(init) && init.Expression.NodeType == "MemberAccess" && strings.HasPrefix(init.Expression.MemberName, "encode") &&
+		init.Expression.Expression != nil && init.Expression.Expression.Name == "abi":
+		return true, false
+	case init.TypeDescriptions != nil && strings.Contains(init.TypeDescriptions.TypeIdentifier, "_storage"):
+		return true, true
+	}
+	return false, false
+}
+
+// writtenDecls collects the declaration IDs assigned or incremented
+// anywhere under node
+func (g *GasOptimizer) writtenDecls(node *SolcASTNode) map[int]bool {
+	written := make(map[int]bool)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		switch n.NodeType {
+		case "Assignment":
+			if n.LeftHandSide != nil {
+				written[baseDecl(n.LeftHandSide)] = true
+			}
+		case "UnaryOperation":
+			if (n.Operator == "++" || n.Operator == "--") && n.SubExpression != nil {
+				written[baseDecl(n.SubExpression)] = true
+			}
+		}
+	})
+	delete(written, 0)
+	return written
+}
+
+// declaredDecls collects the IDs of local variables declared under node,
+// including a for loop's counter
+func (g *GasOptimizer) declaredDecls(node *SolcASTNode) map[int]bool {
+	declared := make(map[int]bool)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		if n.NodeType == "VariableDeclarationStatement" {
+			for _, decl := range n.Declarations {
+				declared[decl.ID] = true
+			}
+		}
+	})
+	return declared
+}
+
+// referencedDecls lists the declaration IDs of identifiers under node,
+// including those in event arguments and, through solc's
+// externalReferences, in inline assembly
+func (g *GasOptimizer) referencedDecls(node *SolcASTNode) []int {
+	var refs []int
+	var collect func(*SolcASTNode)
+	collect = func(node *SolcASTNode) {
+		g.walkSolcAST(node, func(n *SolcASTNode) {
+			switch {
+			case n.NodeType == "Identifier" && n.ReferencedDecl != 0:
+				refs = append(refs, n.ReferencedDecl)
+			case n.NodeType == "EmitStatement" && n.EventCall != nil:
+				collect(n.EventCall)
+			case n.NodeType == "InlineAssembly":
+				for _, ref := range n.ExternalReferences {
+					if ref.Declaration != 0 {
+						refs = append(refs, ref.Declaration)
+					}
+				}
+			}
+		})
+	}
+	collect(node)
+	return refs
+}
+
+// assemblyNames collects the identifiers used in inline assembly under
+// node, without suffixes such as .slot, for ASTs lacking externalReferences
+func (g *GasOptimizer) assemblyNames(node *SolcASTNode) map[string]bool {
+	names := make(map[string]bool)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		if n.NodeType != "InlineAssembly" || n.Assembly == nil {
+			return
+		}
+		walkYul(n.Assembly, func(y *YulNode) {
+			if y.NodeType == "YulIdentifier" {
+				name, _, _ := strings.Cut(y.Name, ".")
+				names[name] = true
+			}
+		})
+	})
+	return names
+}
+
+// baseDecl returns the declaration ID at the root of an lvalue such as
+// x, x[i] or x.field
+func baseDecl(node *SolcASTNode) int {
+	switch node.NodeType {
+	case "Identifier":
+		return node.ReferencedDecl
+	case "IndexAccess":
+		if node.BaseExpression != nil {
+			return baseDecl(node.BaseExpression)
+		}
+	case "MemberAccess":
+		if node.Expression != nil {
+			return baseDecl(node.Expression)
+		}
+	}
+	return 0
+}
+
+// checkMemoryStructParams detects internal/private functions taking a
+// memory struct they only read, which forces every caller to copy it
+func (g *GasOptimizer) checkMemoryStructParams(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil || fn.Parameters == nil ||
+			(fn.Visibility != "internal" && fn.Visibility != "private") {
+			return
+		}
+		written := g.writtenDecls(fn.Body)
+		for idx := range fn.Parameters.Parameters {
+			param := &fn.Parameters.Parameters[idx]
+			if param.StorageLocation != "memory" || !isStructType(param) || written[param.ID] {
+				continue
+			}
+			callers := g.callerArgLocations(ast, fn.ID, idx)
+			location := commonLocation(callers)
+			var suggestion string
+			switch location {
+			case "storage":
+				suggestion = fmt.Sprintf("Callers pass storage; declare '%s' as 'storage' to read fields in place", param.Name)
+			case "calldata":
+				suggestion = fmt.Sprintf("Callers pass calldata; declare '%s' as 'calldata' to avoid the copy", param.Name)
+			default:
+				continue // memory or mixed callers genuinely need the memory copy
+			}
+			g.addReport(Report{
+				RuleID:     RuleMemoryStructParam,
+				Issue:      fmt.Sprintf("Read-only struct parameter '%s' of '%s' is copied to memory", param.Name, fn.Name),
+				Suggestion: suggestion,
+				GasSavings: len(callers) * GasStructCopy,
+				Location:   param.Src,
+			})
+		}
+	})
+}
+
+// callerArgLocations returns the data location of argument idx at every
+// call to the function declared with fnID
+func (g *GasOptimizer) callerArgLocations(ast *SolcASTNode, fnID, idx int) []string {
+	var locations []string
+	g.walkSolcAST(ast, func(n *SolcASTNode) {
+		if n.NodeType != "FunctionCall" || n.Expression == nil || n.Expression.ReferencedDecl != fnID || idx >= len(n.Arguments) {
+			return
+		}
+		locations = append(locations, dataLocation(&n.Arguments[idx]))
+	})
+	return locations
+}
+
+// commonLocation returns the location shared by all callers, or "" when
+// there are none or they disagree
+func commonLocation(locations []string) string {
+	if len(locations) == 0 {
+		return ""
+	}
+	for _, l := range locations[1:] {
+		if l != locations[0] {
+			return ""
+		}
+	}
+	return locations[0]
+}
+
+// dataLocation extracts storage/memory/calldata from an expression's type
+// identifier, e.g. t_struct$_S_$12_storage_ptr
+func dataLocation(node *SolcASTNode) string {
+	if node.TypeDescriptions == nil {
+		return ""
+	}
+	id := node.TypeDescriptions.TypeIdentifier
+	if strings.HasPrefix(id, "t_mapping") {
+		return "storage" // mappings only live in storage
+	}
+	for _, loc := range []string{"storage", "memory", "calldata"} {
+		if strings.HasSuffix(id, "_"+loc) || strings.HasSuffix(id, "_"+loc+"_ptr") {
+			return loc
+		}
+	}
+	return ""
+}
+
+// isStructType reports whether a declaration has a struct type
+func isStructType(node *SolcASTNode) bool {
+	return node.TypeDescriptions != nil && strings.HasPrefix(node.TypeDescriptions.TypeIdentifier, "t_struct")
+}
+
+// checkRepeatedIndexAccess detects the same base[index] read three or more
+// times within a function body
+func (g *GasOptimizer) checkRepeatedIndexAccess(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		counts := make(map[string]int)
+		storage := make(map[string]bool)
+		var order []string
+		g.collectIndexReads(fn.Body, func(access *SolcASTNode) {
+			key := indexKey(access)
+			if key == "" {
+				return
+			}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+			if access.BaseExpression != nil && dataLocation(access.BaseExpression) == "storage" {
+				storage[key] = true
+			}
+		})
+		for _, key := range order {
+			count := counts[key]
+			if count < 3 || g.Config.isExempt(key) {
+				continue
+			}
+			perRead := GasIndexAccess
+			if storage[key] {
+				perRead = g.gas.SloadWarm - g.gas.Mload // reads after the first are warm
+			}
+			g.addReport(Report{
+				RuleID:     RuleRepeatedIndexAccess,
+				Issue:      fmt.Sprintf("'%s' is read %d times in '%s'", key, count, fn.Name),
+				Suggestion: fmt.Sprintf("Cache '%s' in a local variable", key),
+				GasSavings: (count - 1) * perRead,
+				Location:   fn.Src,
+			})
+		}
+	})
+}
+
+// collectIndexReads calls fn for every IndexAccess under node that is read,
+// skipping the target of plain assignments
+func (g *GasOptimizer) collectIndexReads(node *SolcASTNode, fn func(*SolcASTNode)) {
+	g.inspectSolcAST(node, func(n *SolcASTNode) bool {
+		switch {
+		case n.NodeType == "Assignment" && n.Operator == "=" && n.LeftHandSide != nil:
+			if lhs := n.LeftHandSide; lhs.NodeType == "IndexAccess" {
+				for _, sub := range []*SolcASTNode{lhs.BaseExpression, lhs.IndexExpression} {
+					if sub != nil {
+						g.collectIndexReads(sub, fn)
+					}
+				}
+			} else {
+				g.collectIndexReads(lhs, fn)
+			}
+			if n.RightHandSide != nil {
+				g.collectIndexReads(n.RightHandSide, fn)
+			}
+			return false
+		case n.NodeType == "IndexAccess":
+			fn(n)
+		}
+		return true
+	})
+}
+
+// indexKey renders an lvalue-like expression such as data[i], a[i][j] or
+// s.items[k] as a key, or "" for anything more complex
+func indexKey(node *SolcASTNode) string {
+	switch node.NodeType {
+	case "Identifier":
+		return node.Name
+	case "Literal":
+		return node.Value
+	case "IndexAccess":
+		if node.BaseExpression == nil || node.IndexExpression == nil {
+			return ""
+		}
+		base, index := indexKey(node.BaseExpression), indexKey(node.IndexExpression)
+		if base == "" || index == "" {
+			return ""
+		}
+		return base + "[" + index + "]"
+	case "MemberAccess":
+		if node.Expression == nil {
+			return ""
+		}
+		if base := indexKey(node.Expression); base != "" {
+			return base + "." + node.MemberName
+		}
+	}
+	return ""
+}
+
+// checkIncrementInIndex detects arr[i++] style accesses that fold an
+// increment into the index expression
+func (g *GasOptimizer) checkIncrementInIndex(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "IndexAccess" || node.BaseExpression == nil || node.IndexExpression == nil {
+			return
+		}
+		idx := node.IndexExpression
+		if idx.NodeType != "UnaryOperation" || (idx.Operator != "++" && idx.Operator != "--") || idx.SubExpression == nil {
+			return
+		}
+		g.addReport(Report{
+			RuleID:     RuleIncrementInIndex,
+			Issue:      fmt.Sprintf("Index of '%s' combines access with '%s' on '%s'", indexKey(node.BaseExpression), idx.Operator, indexKey(idx.SubExpression)),
+			Suggestion: "Access the element and update the index in separate statements",
+			GasSavings: 0,
+			Location:   node.Src,
+		})
+	})
+}
+
+// modifierInlineThreshold is the complexity x call sites product above
+// which a modifier's inlined copies are reported
+const modifierInlineThreshold = 12
+
+// checkInlinedModifiers detects heavy modifiers whose body is inlined into
+// many functions, multiplying deployed bytecode
+func (g *GasOptimizer) checkInlinedModifiers(ast *SolcASTNode) {
+	uses := make(map[int]int)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "FunctionDefinition" {
+			return
+		}
+		for _, inv := range node.Modifiers {
+			if inv.ModifierName != nil {
+				uses[inv.ModifierName.ReferencedDecl]++
+			}
+		}
+	})
+	stateVars := g.stateVariables(ast)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "ModifierDefinition" || node.Body == nil || uses[node.ID] < 2 {
+			return
+		}
+		statements, storageReads := 0, 0
+		g.walkSolcAST(node.Body, func(n *SolcASTNode) {
+			switch {
+			case strings.HasSuffix(n.NodeType, "Statement") && n.NodeType != "PlaceholderStatement":
+				statements++
+			case n.NodeType == "Identifier" && stateVars[n.ReferencedDecl]:
+				storageReads++
+			}
+		})
+		complexity := statements + storageReads
+		if complexity*uses[node.ID] < modifierInlineThreshold {
+			return
+		}
+		g.addReport(Report{
+			RuleID: RuleInlinedModifier,
+			Issue: fmt.Sprintf("Modifier '%s' (%d statements, %d storage reads) is inlined into %d functions",
+				node.Name, statements, storageReads, uses[node.ID]),
+			Suggestion: fmt.Sprintf("Move the body of '%s' into an internal function and call it from the modifier", node.Name),
+			GasSavings: (uses[node.ID] - 1) * statements * GasInlinedStatement,
+			Location:   node.Src,
+		})
+	})
+}
+
+// stateVariables collects the IDs of contract storage variables, excluding
+// constants and immutables, including those of imported base contracts
+func (g *GasOptimizer) stateVariables(ast *SolcASTNode) map[int]bool {
+	vars := make(map[int]bool)
+	for _, unit := range g.sourceUnits(ast) {
+		g.walkSolcAST(unit, func(node *SolcASTNode) {
+			if node.NodeType != "ContractDefinition" {
+				return
+			}
+			for i := range node.Nodes {
+				if member := &node.Nodes[i]; isStorageVariable(member) {
+					vars[member.ID] = true
+				}
+			}
+		})
+	}
+	return vars
+}
+
+// isStorageVariable reports whether a contract member is a state variable
+// occupying storage
+func isStorageVariable(member *SolcASTNode) bool {
+	return member.NodeType == "VariableDeclaration" && !member.Constant && member.Mutability != "immutable"
+}
+
+// checkExternalSelfCalls detects this.f() calls to a function of the same
+// contract, declared or inherited, which go through a full external CALL
+func (g *GasOptimizer) checkExternalSelfCalls(ast *SolcASTNode) {
+	index := g.contractIndex(ast)
+	g.walkSolcAST(ast, func(contract *SolcASTNode) {
+		if contract.NodeType != "ContractDefinition" {
+			return
+		}
+		functions := make(map[int]bool)
+		for _, c := range linearized(contract, index) {
+			for _, member := range c.Nodes {
+				if member.NodeType == "FunctionDefinition" {
+					functions[member.ID] = true
+				}
+			}
+		}
+		g.walkSolcAST(contract, func(node *SolcASTNode) {
+			if node.NodeType != "FunctionCall" || node.Expression == nil {
+				return
+			}
+			callee := node.Expression
+			if callee.NodeType != "MemberAccess" || callee.Expression == nil ||
+				callee.Expression.Name != "this" || !functions[callee.ReferencedDecl] {
+				return
+			}
+			g.addReport(Report{
+				RuleID:     RuleExternalSelfCall,
+				Issue:      fmt.Sprintf("'this.%s()' makes an external call to the same contract", callee.MemberName),
+				Suggestion: fmt.Sprintf("Call '%s()' internally (make it public or add an internal variant) unless the external call semantics are intended", callee.MemberName),
+				GasSavings: GasSelfCall,
+				Location:   node.Src,
+			})
+		})
+	})
+}
+
+// boolToggleThreshold is the number of writes from which a bool storage
+// flag is considered frequently toggled
+const boolToggleThreshold = 2
+
+// checkToggledBoolFlags detects bool state variables written repeatedly,
+// where a uint256 1/2 flag avoids the zero to non-zero SSTORE
+func (g *GasOptimizer) checkToggledBoolFlags(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(contract *SolcASTNode) {
+		if contract.NodeType != "ContractDefinition" {
+			return
+		}
+		flags := make(map[int]*SolcASTNode)
+		for i := range contract.Nodes {
+			member := &contract.Nodes[i]
+			if isStorageVariable(member) && member.TypeDescriptions != nil && member.TypeDescriptions.TypeString == "bool" {
+				flags[member.ID] = member
+			}
+		}
+		if len(flags) == 0 {
+			return
+		}
+		// Derived contracts write inherited flags too
+		writes := make(map[int]int)
+		g.walkSolcAST(ast, func(node *SolcASTNode) {
+			if node.NodeType == "Assignment" && node.LeftHandSide != nil {
+				if _, ok := flags[node.LeftHandSide.ReferencedDecl]; ok && node.LeftHandSide.NodeType == "Identifier" {
+					writes[node.LeftHandSide.ReferencedDecl]++
+				}
+			}
+		})
+		for _, member := range contract.Nodes {
+			count := writes[member.ID]
+			if _, ok := flags[member.ID]; !ok || count < boolToggleThreshold {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleToggledBoolFlag,
+				Issue:      fmt.Sprintf("bool flag '%s' is written in %d places", member.Name, count),
+				Suggestion: fmt.Sprintf("Store '%s' as uint256 with values 1 and 2 so toggling never writes zero to non-zero", member.Name),
+				GasSavings: g.gas.SstoreSet - g.gas.SstoreReset,
+				Location:   member.Src,
+			})
+		}
+	})
+}
+
+// maxUnrollIterations is the largest literal trip count suggested for unrolling
+const maxUnrollIterations = 4
+
+// checkUnrollableLoops detects for loops with a small literal trip count and
+// no break/continue, where the loop overhead outweighs the body
+func (g *GasOptimizer) checkUnrollableLoops(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "ForStatement" || node.Body == nil {
+			return
+		}
+		iterations, ok := literalTripCount(node)
+		if !ok || iterations < 1 || iterations > maxUnrollIterations {
+			return
+		}
+		jumps := false
+		g.walkSolcAST(node.Body, func(n *SolcASTNode) {
+			if n.NodeType == "Break" || n.NodeType == "Continue" {
+				jumps = true
+			}
+		})
+		if jumps {
+			return
+		}
+		g.addReport(Report{
+			RuleID:     RuleUnrollableLoop,
+			Issue:      fmt.Sprintf("Loop runs a fixed %d iterations", iterations),
+			Suggestion: "Consider unrolling the loop body to drop the counter and bound checks",
+			GasSavings: iterations * GasLoopIteration,
+			Location:   node.Src,
+		})
+	})
+}
+
+// literalTripCount computes the iterations of "for (i = a; i < b; ...)" when
+// b is a number literal and a is a number literal or omitted (0). The
+// update must step by one or by a number literal, as in i += 2.
+func literalTripCount(loop *SolcASTNode) (int, bool) {
+	cond := loop.Condition
+	if cond == nil || cond.NodeType != "BinaryOperation" || cond.RightExpression == nil ||
+		(cond.Operator != "<" && cond.Operator != "<=") {
+		return 0, false
+	}
+	bound, ok := numberLiteral(cond.RightExpression)
+	if !ok {
+		return 0, false
+	}
+	start := 0
+	if init := loop.InitializationExpression; init != nil {
+		var value *SolcASTNode
+		switch init.NodeType {
+		case "VariableDeclarationStatement":
+			value = init.InitialValue
+		case "ExpressionStatement":
+			if init.Expression != nil {
+				value = init.Expression.RightHandSide
+			}
+		}
+		if value != nil {
+			if start, ok = numberLiteral(value); !ok {
+				return 0, false
+			}
+		}
+	}
+	if cond.Operator == "<=" {
+		bound++
+	}
+	step := 1
+	if update := loop.LoopExpression; update != nil {
+		if update.NodeType == "ExpressionStatement" && update.Expression != nil {
+			update = update.Expression
+		}
+		if update.NodeType == "Assignment" {
+			if update.Operator != "+=" || update.RightHandSide == nil {
+				return 0, false
+			}
+			if step, ok = numberLiteral(update.RightHandSide); !ok || step <= 0 {
+				return 0, false
+			}
+		}
+	}
+	return (bound - start + step - 1) / step, true
+}
+
+// numberLiteral parses a decimal number literal
+func numberLiteral(node *SolcASTNode) (int, bool) {
+	if node == nil || node.NodeType != "Literal" || node.Kind != "number" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(node.Value)
+	return n, err == nil
+}
+
+// Thresholds for checkManyReturnValues
+const (
+	manyReturnValues  = 4 // return values from which a struct is suggested
+	smallReturnValues = 2 // of which at least this many must be sub-word types
+)
+
+// checkManyReturnValues detects functions returning many values, several of
+// them narrower than a word, that could return one struct instead
+func (g *GasOptimizer) checkManyReturnValues(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.ReturnParameters == nil {
+			return
+		}
+		returns := fn.ReturnParameters.Parameters
+		if len(returns) < manyReturnValues {
+			return
+		}
+		small := 0
+		for i := range returns {
+			if typeBits(declTypeString(&returns[i])) < 256 {
+				small++
+			}
+		}
+		if small < smallReturnValues {
+			return
+		}
+		g.addReport(Report{
+			RuleID:     RuleManyReturnValues,
+			Issue:      fmt.Sprintf("Function '%s' returns %d values, %d of them narrower than 256 bits", fn.Name, len(returns), small),
+			Suggestion: "Return a struct grouping the values, ordering the small fields together",
+			GasSavings: 0,
+			Location:   fn.Src,
+		})
+	})
+}
+
+// declTypeString returns the Solidity type of a declaration
+func declTypeString(decl *SolcASTNode) string {
+	if decl.TypeDescriptions != nil {
+		return decl.TypeDescriptions.TypeString
+	}
+	if decl.TypeName != nil {
+		return decl.TypeName.Name
+	}
+	return ""
+}
+
+// typeBits returns the bit width of an elementary value type, or 256 for
+// full-word and reference types
+func typeBits(typeString string) int {
+	switch {
+	case typeString == "bool":
+		return 8
+	case typeString == "address" || typeString == "address payable":
+		return 160
+	case strings.HasPrefix(typeString, "uint"), strings.HasPrefix(typeString, "int"):
+		if n, err := strconv.Atoi(strings.TrimLeft(typeString, "uint")); err == nil {
+			return n
+		}
+	case strings.HasPrefix(typeString, "bytes"):
+		if n, err := strconv.Atoi(strings.TrimPrefix(typeString, "bytes")); err == nil {
+			return n * 8
+		}
+	}
+	return 256
+}
+
+// safeMathFunctions are SafeMath members duplicated by checked arithmetic
+var safeMathFunctions = map[string]string{"add": "+", "sub": "-", "mul": "*", "div": "/", "mod": "%"}
+
+// checkSafeMathOnChecked detects SafeMath calls in sources requiring
+// Solidity 0.8+, where arithmetic is already overflow-checked. Only calls
+// resolving to a library named SafeMath or defined in OpenZeppelin's
+// SafeMath.sol count, so other add/sub helpers, such as saturating or
+// fixed-point math attached with using-for, are left alone.
+func (g *GasOptimizer) checkSafeMathOnChecked(ast *SolcASTNode) {
+	if min, ok := g.pragmaMinVersion(ast); !ok || !min.atLeast(solcVersion{0, 8, 0}) {
+		return
+	}
+	safeMath := g.safeMathFunctions(ast)
+	if len(safeMath) == 0 {
+		return
+	}
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "FunctionCall" || node.Expression == nil || node.Expression.NodeType != "MemberAccess" {
+			return
+		}
+		callee := node.Expression
+		op, ok := safeMathFunctions[callee.MemberName]
+		if !ok || !safeMath[callee.ReferencedDecl] {
+			return
+		}
+		g.addReport(Report{
+			RuleID:     RuleSafeMathOnChecked,
+			Issue:      fmt.Sprintf("SafeMath '%s' used with Solidity >= 0.8 checked arithmetic", callee.MemberName),
+			Suggestion: fmt.Sprintf("Use the native '%s' operator and drop SafeMath", op),
+			GasSavings: GasSafeMathCall,
+			Location:   node.Src,
+		})
+	})
+}
+
+// safeMathFunctions collects the IDs of the functions of SafeMath libraries
+// in the analyzed source and its imports: libraries named SafeMath, and
+// those defined in OpenZeppelin's SafeMath.sol or SignedSafeMath.sol,
+// including their Upgradeable variants
+func (g *GasOptimizer) safeMathFunctions(ast *SolcASTNode) map[int]bool {
+	ids := make(map[int]bool)
+	for _, unit := range g.sourceUnits(ast) {
+		file := strings.TrimSuffix(strings.TrimSuffix(path.Base(unit.AbsolutePath), ".sol"), "Upgradeable")
+		openZeppelin := strings.Contains(strings.ToLower(unit.AbsolutePath), "openzeppelin") &&
+			(file == "SafeMath" || file == "SignedSafeMath")
+		g.walkSolcAST(unit, func(lib *SolcASTNode) {
+			if lib.NodeType != "ContractDefinition" || lib.ContractKind != "library" || (lib.Name != "SafeMath" && !openZeppelin) {
+				return
+			}
+			for i := range lib.Nodes {
+				if fn := &lib.Nodes[i]; fn.NodeType == "FunctionDefinition" {
+					ids[fn.ID] = true
+				}
+			}
+		})
+	}
+	return ids
+}
+
+// checkNewInLoops detects contract deployments with new inside loop bodies
+func (g *GasOptimizer) checkNewInLoops(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		if (loop.NodeType != "ForStatement" && loop.NodeType != "WhileStatement" && loop.NodeType != "DoWhileStatement") || loop.Body == nil {
+			return
+		}
+		g.inspectSolcAST(loop.Body, func(node *SolcASTNode) bool {
+			if node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" || node.NodeType == "DoWhileStatement" {
+				return false // reported against the innermost loop
+			}
+			if node.NodeType != "FunctionCall" || !isContractCreation(node) {
+				return true
+			}
+			contract := declTypeString(node)
+			savings, note := g.loopSavings(loop, fixedSavings(g.gas.Create))
+			g.addReport(Report{
+				RuleID:        RuleNewInLoop,
+				Issue:         fmt.Sprintf("'new' deploys %s on every loop iteration%s", strings.TrimPrefix(contract, "contract "), note),
+				Suggestion:    "Deploy EIP-1167 minimal proxy clones of one implementation, or move the deployment out of the loop",
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      node.Src,
+			})
+			return true
+		})
+	})
+}
+
+// isContractCreation reports whether call is new C(...) for a contract type,
+// as opposed to new uint[](n) memory array allocation
+func isContractCreation(call *SolcASTNode) bool {
+	callee := call.Expression
+	if callee == nil {
+		return false
+	}
+	// new C{value: v}(...) wraps the NewExpression in FunctionCallOptions
+	if callee.NodeType == "FunctionCallOptions" && callee.Expression != nil {
+		callee = callee.Expression
+	}
+	return callee.NodeType == "NewExpression" && strings.HasPrefix(declTypeString(call), "contract ")
+}
+
+// checkEncodeWithSignature detects abi.encodeWithSignature calls with a
+// literal signature, which is hashed to a selector on every call
+func (g *GasOptimizer) checkEncodeWithSignature(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "FunctionCall" || node.Expression == nil || node.Expression.NodeType != "MemberAccess" {
+			return
+		}
+		callee := node.Expression
+		if callee.MemberName != "encodeWithSignature" || callee.Expression == nil || callee.Expression.Name != "abi" {
+			return
+		}
+		if len(node.Arguments) == 0 || node.Arguments[0].NodeType != "Literal" || node.Arguments[0].Kind != "string" {
+			return
+		}
+		signature := node.Arguments[0].Value
+		name := signature
+		if i := strings.Index(signature, "("); i >= 0 {
+			name = signature[:i]
+		}
+		words := (len(signature) + 31) / 32
+		g.addReport(Report{
+			RuleID:     RuleEncodeWithSignature,
+			Issue:      fmt.Sprintf("abi.encodeWithSignature hashes \"%s\" at runtime", signature),
+			Suggestion: fmt.Sprintf("Use abi.encodeWithSelector(I.%s.selector, ...) or abi.encodeCall so the selector is a compile-time constant", name),
+			GasSavings: g.gas.Keccak + words*g.gas.KeccakWord,
+			Location:   node.Src,
+		})
+	})
+}
+
+// checkRepeatedHashKeys detects the same keccak256(...) computed more than
+// once in a function to index a mapping, e.g. balances[keccak256(abi.encodePacked(a, b))]
+func (g *GasOptimizer) checkRepeatedHashKeys(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		written := g.writtenDecls(fn.Body)
+		counts := make(map[string]int)
+		words := make(map[string]int)
+		var order []string
+		g.walkSolcAST(fn.Body, func(access *SolcASTNode) {
+			if access.NodeType != "IndexAccess" || access.IndexExpression == nil {
+				return
+			}
+			hash := access.IndexExpression
+			if hash.NodeType != "FunctionCall" || hash.Expression == nil || hash.Expression.Name != "keccak256" {
+				return
+			}
+			key := exprKey(hash)
+			if key == "" {
+				return
+			}
+			for _, ref := range g.referencedDecls(hash) {
+				if written[ref] {
+					return // inputs change between computations
+				}
+			}
+			if counts[key] == 0 {
+				order = append(order, key)
+				words[key] = hashedWords(hash)
+			}
+			counts[key]++
+		})
+		for _, key := range order {
+			count := counts[key]
+			if count < 2 {
+				continue
+			}
+			perHash := g.gas.Keccak + words[key]*g.gas.KeccakWord + GasMemoryAlloc
+			g.addReport(Report{
+				RuleID:     RuleRepeatedHashKey,
+				Issue:      fmt.Sprintf("Mapping key '%s' is computed %d times in '%s'", key, count, fn.Name),
+				Suggestion: "Compute the key once into a bytes32 local and index with it",
+				GasSavings: (count - 1) * perHash,
+				Location:   fn.Src,
+			})
+		}
+	})
+}
+
+// exprKey is indexKey extended to function calls such as
+// keccak256(abi.encodePacked(a, b)), or "" for anything more complex
+func exprKey(node *SolcASTNode) string {
+	if node.NodeType != "FunctionCall" {
+		return indexKey(node)
+	}
+	if node.Expression == nil {
+		return ""
+	}
+	callee := exprKey(node.Expression)
+	if callee == "" {
+		return ""
+	}
+	args := make([]string, len(node.Arguments))
+	for i := range node.Arguments {
+		if args[i] = exprKey(&node.Arguments[i]); args[i] == "" {
+			return ""
+		}
+	}
+	return callee + "(" + strings.Join(args, ", ") + ")"
+}
+
+// hashedWords estimates the 32-byte words hashed by a keccak256 call, taking
+// one word per argument of an inner abi.encode/encodePacked
+func hashedWords(hash *SolcASTNode) int {
+	if len(hash.Arguments) == 1 && hash.Arguments[0].NodeType == "FunctionCall" {
+		if n := len(hash.Arguments[0].Arguments); n > 0 {
+			return n
+		}
+	}
+	return 1
+}
+
+// stateAccess classifies what a function body does to contract state
+type stateAccess int
+
+const (
+	accessNone  stateAccess = iota // could be pure
+	accessRead                     // could be view
+	accessWrite                    // modifies state, or unknown
+)
+
+// checkMissingMutability detects non-payable functions whose bodies never
+// write state, suggesting view or pure
+func (g *GasOptimizer) checkMissingMutability(ast *SolcASTNode) {
+	contractVars := make(map[int]bool) // non-constant contract variables, immutables included
+	mutability := make(map[int]string) // function ID -> stateMutability
+	for _, unit := range g.sourceUnits(ast) {
+		g.walkSolcAST(unit, func(node *SolcASTNode) {
+			switch node.NodeType {
+			case "ContractDefinition":
+				for i := range node.Nodes {
+					if member := &node.Nodes[i]; member.NodeType == "VariableDeclaration" && !member.Constant {
+						contractVars[member.ID] = true
+					}
+				}
+			case "FunctionDefinition":
+				mutability[node.ID] = node.StateMutability
+			}
+		})
+	}
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil || fn.StateMutability != "nonpayable" ||
+			(fn.Kind != "" && fn.Kind != "function") || fn.Virtual || len(fn.Modifiers) > 0 {
+			return // modifiers and overriders are not visible here
+		}
+		suggest := "pure"
+		switch g.functionStateAccess(fn, contractVars, mutability) {
+		case accessWrite:
+			return
+		case accessRead:
+			suggest = "view"
+		}
+		g.addReport(Report{
+			RuleID:     RuleMissingMutability,
+			Issue:      fmt.Sprintf("Function '%s' does not modify state but is not marked %s", fn.Name, suggest),
+			Suggestion: fmt.Sprintf("Declare '%s' as %s", fn.Name, suggest),
+			GasSavings: GasMutability,
+			Location:   fn.Src,
+		})
+	})
+}
+
+// functionStateAccess reports the strongest state access in fn's body,
+// treating anything it cannot classify as a write
+func (g *GasOptimizer) functionStateAccess(fn *SolcASTNode, contractVars map[int]bool, mutability map[int]string) stateAccess {
+	// Storage pointers (parameters or locals) alias contract state
+	state := make(map[int]bool, len(contractVars))
+	for id := range contractVars {
+		state[id] = true
+	}
+	if fn.Parameters != nil {
+		for _, param := range fn.Parameters.Parameters {
+			if param.StorageLocation == "storage" {
+				state[param.ID] = true
+			}
+		}
+	}
+	g.walkSolcAST(fn.Body, func(n *SolcASTNode) {
+		for _, decl := range n.Declarations {
+			if decl.StorageLocation == "storage" {
+				state[decl.ID] = true
+			}
+		}
+	})
+
+	access := accessNone
+	note := func(a stateAccess) {
+		access = max(access, a)
+	}
+	g.inspectSolcAST(fn.Body, func(n *SolcASTNode) bool {
+		switch n.NodeType {
+		case "InlineAssembly", "EmitStatement", "NewExpression", "FunctionCallOptions":
+			note(accessWrite)
+		case "Assignment":
+			if n.LeftHandSide != nil && writesState(n.LeftHandSide, state) {
+				note(accessWrite)
+			}
+		case "UnaryOperation":
+			if (n.Operator == "++" || n.Operator == "--" || n.Operator == "delete") &&
+				n.SubExpression != nil && writesState(n.SubExpression, state) {
+				note(accessWrite)
+			}
+		case "Identifier":
+			if state[n.ReferencedDecl] || n.Name == "this" {
+				note(accessRead)
+			}
+		case "MemberAccess":
+			switch {
+			case n.MemberName == "call" || n.MemberName == "delegatecall" || n.MemberName == "transfer" ||
+				n.MemberName == "send" || n.MemberName == "push" || n.MemberName == "pop":
+				note(accessWrite)
+			case n.MemberName == "balance" || n.MemberName == "staticcall" || n.MemberName == "code" || n.MemberName == "codehash":
+				note(accessRead)
+			case n.Expression != nil && (n.Expression.Name == "block" || n.Expression.Name == "tx" || n.Expression.Name == "msg"):
+				note(accessRead)
+			}
+		case "FunctionCall":
+			if n.Kind == "functionCall" && n.Expression != nil {
+				note(calleeAccess(n.Expression, mutability))
+			}
+		}
+		return access != accessWrite
+	})
+	return access
+}
+
+// writesState reports whether assigning to lhs changes contract storage
+func writesState(lhs *SolcASTNode, state map[int]bool) bool {
+	return state[baseDecl(lhs)] || dataLocation(lhs) == "storage"
+}
+
+// calleeAccess classifies a call by its callee: functions of known
+// mutability, builtins, or unknown (external) calls treated as writes
+func calleeAccess(callee *SolcASTNode, mutability map[int]string) stateAccess {
+	if m, ok := mutability[callee.ReferencedDecl]; ok && callee.ReferencedDecl != 0 {
+		switch m {
+		case "pure":
+			return accessNone
+		case "view":
+			return accessRead
+		}
+		return accessWrite
+	}
+	switch {
+	case callee.NodeType == "Identifier" && callee.ReferencedDecl < 0: // builtin
+		switch callee.Name {
+		case "gasleft", "blockhash", "blobhash":
+			return accessRead
+		case "selfdestruct":
+			return accessWrite
+		}
+		return accessNone
+	case callee.NodeType == "MemberAccess" && callee.Expression != nil && callee.Expression.ReferencedDecl < 0:
+		return accessNone // abi.encode, string.concat and similar
+	}
+	return accessWrite
+}
+
+// checkLoopConstantReads reports constant and immutable variables read
+// inside loops. Unlike storage reads (GAS001) they cost no SLOAD: constants
+// are inlined and immutables are PUSHed from code, so at most a local copy
+// saves a few gas. They are surfaced at info severity to explain why such
+// reads are not flagged alongside storage reads.
+func (g *GasOptimizer) checkLoopConstantReads(ast *SolcASTNode) {
+	kinds := make(map[int]string) // declaration ID -> "constant" or "immutable"
+	for _, unit := range g.sourceUnits(ast) {
+		g.walkSolcAST(unit, func(node *SolcASTNode) {
+			if node.NodeType != "ContractDefinition" {
+				return
+			}
+			for i := range node.Nodes {
+				switch member := &node.Nodes[i]; {
+				case member.NodeType != "VariableDeclaration":
+				case member.Constant || member.Mutability == "constant":
+					kinds[member.ID] = "constant"
+				case member.Mutability == "immutable":
+					kinds[member.ID] = "immutable"
+				}
+			}
+		})
+	}
+	if len(kinds) == 0 {
+		return
+	}
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		if (loop.NodeType != "ForStatement" && loop.NodeType != "WhileStatement" && loop.NodeType != "DoWhileStatement") || loop.Body == nil {
+			return
+		}
+		counts := make(map[int]int)
+		names := make(map[int]string)
+		var order []int
+		count := func(node *SolcASTNode) bool {
+			if node != loop && (node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" || node.NodeType == "DoWhileStatement") {
+				return false // reported against the innermost loop
+			}
+			if _, ok := kinds[node.ReferencedDecl]; ok && node.NodeType == "Identifier" {
+				if counts[node.ReferencedDecl] == 0 {
+					order = append(order, node.ReferencedDecl)
+				}
+				counts[node.ReferencedDecl]++
+				names[node.ReferencedDecl] = node.Name
+			}
+			return true
+		}
+		// The condition and step run every iteration too
+		for _, part := range [...]*SolcASTNode{loop.Condition, loop.LoopExpression, loop.Body} {
+			if part != nil {
+				g.inspectSolcAST(part, count)
+			}
+		}
+		for _, id := range order {
+			issue := fmt.Sprintf("constant '%s' is read in a loop (%d reads); it is inlined, so no SLOAD is involved", names[id], counts[id])
+			suggestion := "No change needed"
+			var savings savingsRange
+			if kinds[id] == "immutable" {
+				var note string
+				savings, note = g.loopSavings(loop, fixedSavings(counts[id]*g.gas.Mload))
+				issue = fmt.Sprintf("immutable '%s' is read in a loop (%d reads per iteration); it is embedded in code, so no SLOAD is involved%s", names[id], counts[id], note)
+				suggestion = fmt.Sprintf("Optionally copy '%s' to a local before the loop; savings are marginal", names[id])
+			}
+			g.addReport(Report{
+				RuleID:        RuleLoopConstantRead,
+				Issue:         issue,
+				Suggestion:    suggestion,
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      loop.Src,
+			})
+		}
+	})
+}
+
+// checkRepeatedExternalCalls detects identical external view/pure calls,
+// such as token.balanceOf(x) twice, within a function. A state-changing
+// external call in between may change the result, so counting restarts
+// after one.
+func (g *GasOptimizer) checkRepeatedExternalCalls(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		written := g.writtenDecls(fn.Body)
+		counts := make(map[string]int)
+		var order []string
+		flush := func() {
+			for _, key := range order {
+				if count := counts[key]; count >= 2 {
+					g.addReport(Report{
+						RuleID:     RuleRepeatedExternal,
+						Issue:      fmt.Sprintf("External call '%s' is made %d times in '%s'", key, count, fn.Name),
+						Suggestion: "Call it once and keep the result in a local variable",
+						GasSavings: (count - 1) * GasExternalCall,
+						Location:   fn.Src,
+					})
+				}
+			}
+			counts, order = make(map[string]int), nil
+		}
+		g.walkSolcAST(fn.Body, func(call *SolcASTNode) {
+			mutability, ok := externalCallMutability(call)
+			if !ok {
+				return
+			}
+			if mutability != "view" && mutability != "pure" {
+				flush()
+				return
+			}
+			key := exprKey(call)
+			if key == "" {
+				return
+			}
+			for _, ref := range g.referencedDecls(call) {
+				if written[ref] {
+					return // target or arguments change within the function
+				}
+			}
+			if counts[key] == 0 {
+				order = append(order, key)
+			}
+			counts[key]++
+		})
+		flush()
+	})
+}
+
+// externalCallMutability returns the state mutability of an external
+// function call, read from the callee's function type
+func externalCallMutability(call *SolcASTNode) (string, bool) {
+	if call.NodeType != "FunctionCall" || call.Expression == nil {
+		return "", false
+	}
+	callee := call.Expression
+	if callee.NodeType == "FunctionCallOptions" {
+		return "payable", true // {value: ...} or {gas: ...} calls are never cached
+	}
+	if callee.NodeType != "MemberAccess" || callee.TypeDescriptions == nil {
+		return "", false
+	}
+	switch callee.MemberName {
+	case "call", "delegatecall", "transfer", "send":
+		return "payable", true // low-level calls and ether transfers
+	}
+	fields := strings.Fields(callee.TypeDescriptions.TypeString)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "function") {
+		return "", false
+	}
+	external, mutability := false, "nonpayable"
+	for _, f := range fields {
+		switch f {
+		case "external":
+			external = true
+		case "view", "pure", "payable":
+			mutability = f
+		case "returns":
+			return mutability, external
+		}
+	}
+	return mutability, external
+}
+
+// checkZeroAddressChecks detects repeated require(x != address(0)) checks
+// of the same variable within a function (GAS024) and, as a separate
+// safety rule, address parameters sent ether or tokens without any zero
+// check (GAS025)
+func (g *GasOptimizer) checkZeroAddressChecks(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		g.checkDuplicateZeroChecks(fn)
+		g.checkMissingZeroChecks(fn)
+	})
+}
+
+// checkDuplicateZeroChecks reports zero checks of a variable already
+// checked earlier in the function body and not reassigned since
+func (g *GasOptimizer) checkDuplicateZeroChecks(fn *SolcASTNode) {
+	checked := make(map[string]bool)
+	for i := range fn.Body.Statements {
+		stmt := &fn.Body.Statements[i]
+		if stmt.NodeType == "ExpressionStatement" && stmt.Expression != nil && isRequireCall(stmt.Expression) {
+			if key := zeroCheckedKey(&stmt.Expression.Arguments[0]); key != "" {
+				if checked[key] {
+					g.addReport(Report{
+						RuleID:     RuleDuplicateZeroCheck,
+						Issue:      fmt.Sprintf("'%s' is checked against address(0) again in '%s'", key, fn.Name),
+						Suggestion: "Remove the repeated check",
+						GasSavings: GasConditionCheck,
+						Location:   stmt.Src,
+					})
+				}
+				checked[key] = true
+				continue
+			}
+		}
+		// A reassignment makes a later check meaningful again
+		g.walkSolcAST(stmt, func(n *SolcASTNode) {
+			if n.NodeType == "Assignment" && n.LeftHandSide != nil {
+				delete(checked, indexKey(n.LeftHandSide))
+			}
+		})
+	}
+}
+
+// checkMissingZeroChecks reports address parameters used as ether or token
+// transfer targets that the function never compares with address(0)
+func (g *GasOptimizer) checkMissingZeroChecks(fn *SolcASTNode) {
+	if fn.Parameters == nil || (fn.Visibility != "public" && fn.Visibility != "external") {
+		return
+	}
+	params := make(map[int]string)
+	for _, param := range fn.Parameters.Parameters {
+		if strings.HasPrefix(declTypeString(&param), "address") {
+			params[param.ID] = param.Name
+		}
+	}
+	// Parameters handed to modifiers may be checked there
+	for _, mod := range fn.Modifiers {
+		for i := range mod.Arguments {
+			for _, ref := range g.referencedDecls(&mod.Arguments[i]) {
+				delete(params, ref)
+			}
+		}
+	}
+	if len(params) == 0 {
+		return
+	}
+	targets := make(map[int]*SolcASTNode) // parameter ID -> first transfer using it
+	checked := make(map[int]bool)
+	g.walkSolcAST(fn.Body, func(n *SolcASTNode) {
+		if n.NodeType == "BinaryOperation" && (n.Operator == "==" || n.Operator == "!=") &&
+			n.LeftExpression != nil && n.RightExpression != nil {
+			for _, pair := range [...][2]*SolcASTNode{{n.LeftExpression, n.RightExpression}, {n.RightExpression, n.LeftExpression}} {
+				if isZeroAddress(pair[1]) && pair[0].NodeType == "Identifier" {
+					checked[pair[0].ReferencedDecl] = true
+				}
+			}
+		}
+		if target := transferTarget(n); target != nil && target.NodeType == "Identifier" {
+			if _, ok := params[target.ReferencedDecl]; ok && targets[target.ReferencedDecl] == nil {
+				targets[target.ReferencedDecl] = n
+			}
+		}
+	})
+	for _, param := range fn.Parameters.Parameters {
+		call := targets[param.ID]
+		if call == nil || checked[param.ID] {
+			continue
+		}
+		g.addReport(Report{
+			RuleID:     RuleMissingZeroCheck,
+			Issue:      fmt.Sprintf("'%s' receives a transfer in '%s' but is never checked against address(0)", param.Name, fn.Name),
+			Suggestion: fmt.Sprintf("Add require(%s != address(0)) or a custom error check", param.Name),
+			GasSavings: 0,
+			Location:   call.Src,
+		})
+	}
+}
+
+// isRequireCall reports whether node is require(cond, ...) or assert(cond)
+func isRequireCall(node *SolcASTNode) bool {
+	return node.NodeType == "FunctionCall" && node.Expression != nil &&
+		(node.Expression.Name == "require" || node.Expression.Name == "assert") && len(node.Arguments) > 0
+}
+
+// zeroCheckedKey returns the variable in a x != address(0) condition, or ""
+func zeroCheckedKey(cond *SolcASTNode) string {
+	if cond.NodeType != "BinaryOperation" || cond.Operator != "!=" || cond.LeftExpression == nil || cond.RightExpression == nil {
+		return ""
+	}
+	switch {
+	case isZeroAddress(cond.RightExpression):
+		return indexKey(cond.LeftExpression)
+	case isZeroAddress(cond.LeftExpression):
+		return indexKey(cond.RightExpression)
+	}
+	return ""
+}
+
+// isZeroAddress reports whether node is address(0)
+func isZeroAddress(node *SolcASTNode) bool {
+	return node.NodeType == "FunctionCall" && node.Expression != nil &&
+		node.Expression.NodeType == "ElementaryTypeNameExpression" &&
+		node.Expression.TypeName != nil && node.Expression.TypeName.Name == "address" &&
+		len(node.Arguments) == 1 && node.Arguments[0].NodeType == "Literal" && node.Arguments[0].Value == "0"
+}
+
+// transferTarget returns the recipient of an ether transfer (to.transfer,
+// to.send, to.call) or token transfer (transfer(to, ...),
+// transferFrom(from, to, ...) and their safe variants), unwrapping
+// payable(to), or nil
+func transferTarget(call *SolcASTNode) *SolcASTNode {
+	if call.NodeType != "FunctionCall" || call.Expression == nil {
+		return nil
+	}
+	callee := call.Expression
+	if callee.NodeType == "FunctionCallOptions" && callee.Expression != nil {
+		callee = callee.Expression // to.call{value: v}(...)
+	}
+	if callee.NodeType != "MemberAccess" || callee.Expression == nil {
+		return nil
+	}
+	var target *SolcASTNode
+	switch callee.MemberName {
+	case "transfer", "send", "call":
+		if callee.MemberName == "transfer" && len(call.Arguments) == 2 {
+			target = &call.Arguments[0] // token.transfer(to, amount)
+		} else {
+			target = callee.Expression
+		}
+	case "safeTransfer":
+		if len(call.Arguments) >= 2 {
+			target = &call.Arguments[len(call.Arguments)-2] // token.safeTransfer(to, amount) or SafeERC20.safeTransfer(token, to, amount)
+		}
+	case "transferFrom", "safeTransferFrom":
+		if len(call.Arguments) >= 3 {
+			target = &call.Arguments[1]
+		}
+	}
+	// payable(to)
+	for target != nil && target.NodeType == "FunctionCall" && len(target.Arguments) == 1 &&
+		target.Expression != nil && target.Expression.NodeType == "ElementaryTypeNameExpression" {
+		target = &target.Arguments[0]
+	}
+	return target
+}
+
+// checkMappingExistence detects conditions such as balances[x] == 0 that
+// use a mapping's default value to test whether a key exists, which cannot
+// tell an absent key from a stored zero
+func (g *GasOptimizer) checkMappingExistence(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		var cond *SolcASTNode
+		switch {
+		case node.NodeType == "IfStatement" || node.NodeType == "Conditional":
+			cond = node.Condition
+		case isRequireCall(node):
+			cond = &node.Arguments[0]
+		}
+		if cond == nil {
+			return
+		}
+		g.walkSolcAST(cond, func(cmp *SolcASTNode) {
+			if cmp.NodeType != "BinaryOperation" || (cmp.Operator != "==" && cmp.Operator != "!=") ||
+				cmp.LeftExpression == nil || cmp.RightExpression == nil {
+				return
+			}
+			access, zero := cmp.LeftExpression, cmp.RightExpression
+			if isZeroLiteral(access) {
+				access, zero = zero, access
+			}
+			if !isZeroLiteral(zero) || access.NodeType != "IndexAccess" || access.BaseExpression == nil ||
+				!strings.HasPrefix(declTypeString(access.BaseExpression), "mapping(") {
+				return
+			}
+			key := indexKey(access)
+			if key == "" {
+				key = "mapping value"
+			}
+			g.addReport(Report{
+				RuleID:     RuleMappingExistence,
+				Issue:      fmt.Sprintf("'%s %s 0' tests key existence through the default value; a stored zero looks absent", key, cmp.Operator),
+				Suggestion: "Track membership explicitly (a separate exists mapping or a non-zero sentinel)",
+				GasSavings: 0,
+				Location:   cmp.Src,
+			})
+		})
+	})
+}
+
+// isZeroLiteral reports whether node is the number literal 0
+func isZeroLiteral(node *SolcASTNode) bool {
+	return node.NodeType == "Literal" && node.Kind == "number" && node.Value == "0"
+}
+
+// checkLoopStorageWrites detects storage array elements assigned inside a
+// loop, each of which pays an SSTORE plus a length bounds check and slot hash
+func (g *GasOptimizer) checkLoopStorageWrites(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		if (loop.NodeType != "ForStatement" && loop.NodeType != "WhileStatement" && loop.NodeType != "DoWhileStatement") || loop.Body == nil {
+			return
+		}
+		g.inspectSolcAST(loop.Body, func(node *SolcASTNode) bool {
+			if node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" || node.NodeType == "DoWhileStatement" {
+				return false // reported against the innermost loop
+			}
+			if node.NodeType != "Assignment" || node.LeftHandSide == nil {
+				return true
+			}
+			lhs := node.LeftHandSide
+			if lhs.NodeType != "IndexAccess" || lhs.BaseExpression == nil || !isStorageArray(lhs.BaseExpression) {
+				return true
+			}
+			name := exprKey(lhs.BaseExpression)
+			if name == "" {
+				name = "storage array"
+			}
+			savings, note := g.loopSavings(loop, savingsRange{g.gas.SloadWarm + g.gas.Keccak, g.gas.SloadCold + g.gas.Keccak})
+			g.addReport(Report{
+				RuleID:        RuleLoopStorageWrite,
+				Issue:         fmt.Sprintf("%s[...] is written on every loop iteration (SSTORE, ~%d gas each, plus a length check)%s", name, g.gas.SstoreReset, note),
+				Suggestion:    "For a freshly filled array, build it in a memory array and assign it to storage once; otherwise use unchecked index math",
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      node.Src,
+			})
+			return true
+		})
+	})
+}
+
+// isStorageArray reports whether node is a storage array, as opposed to a
+// mapping, bytes or a memory array
+func isStorageArray(node *SolcASTNode) bool {
+	return node.TypeDescriptions != nil && strings.HasPrefix(node.TypeDescriptions.TypeIdentifier, "t_array") &&
+		dataLocation(node) == "storage"
+}
+
+// checkBoundedLoops detects require(n <= C) followed in the same block by
+// for (...; i < n; ++i), where C is a literal or constant. The cap proves
+// the counter cannot overflow, so its increment can be unchecked. Solidity
+// 0.8.22 does this itself for such loops, so only older pragmas are checked.
+func (g *GasOptimizer) checkBoundedLoops(ast *SolcASTNode) {
+	min, ok := g.pragmaMinVersion(ast)
+	if !ok || !min.atLeast(solcVersion{0, 8, 0}) || min.atLeast(solcVersion{0, 8, 22}) {
+		return
+	}
+	constants := g.constantDecls(ast)
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		if block.NodeType != "Block" {
+			return
+		}
+		caps := make(map[int]string) // declaration ID -> the require'd cap
+		for i := range block.Statements {
+			stmt := &block.Statements[i]
+			if stmt.NodeType == "ExpressionStatement" && stmt.Expression != nil && isRequireCall(stmt.Expression) {
+				if id, limit := requireCap(&stmt.Expression.Arguments[0], constants); id != 0 {
+					caps[id] = limit
+				}
+				continue
+			}
+			if stmt.NodeType == "ForStatement" {
+				g.reportBoundedLoop(stmt, caps)
+			}
+			for id := range g.writtenDecls(stmt) {
+				delete(caps, id)
+			}
+		}
+	})
+}
+
+// constantDecls collects the IDs of constant variable declarations
+func (g *GasOptimizer) constantDecls(ast *SolcASTNode) map[int]bool {
+	constants := make(map[int]bool)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType == "VariableDeclaration" && (node.Constant || node.Mutability == "constant") {
+			constants[node.ID] = true
+		}
+	})
+	return constants
+}
+
+// reportBoundedLoop reports a for loop whose bound n was capped by an
+// earlier require and whose counter is only changed by its checked step
+func (g *GasOptimizer) reportBoundedLoop(loop *SolcASTNode, caps map[int]string) {
+	cond := loop.Condition
+	if cond == nil || cond.NodeType != "BinaryOperation" || (cond.Operator != "<" && cond.Operator != "<=") ||
+		cond.LeftExpression == nil || cond.RightExpression == nil || cond.RightExpression.NodeType != "Identifier" {
+		return
+	}
+	limit, capped := caps[cond.RightExpression.ReferencedDecl]
+	counter := baseDecl(cond.LeftExpression)
+	if !capped || counter == 0 || loop.LoopExpression == nil || loop.LoopExpression.Expression == nil ||
+		loop.Body == nil || g.writtenDecls(loop.Body)[counter] {
+		return
+	}
+	step := loop.LoopExpression.Expression
+	increments := (step.NodeType == "UnaryOperation" && step.Operator == "++" && step.SubExpression != nil &&
+		baseDecl(step.SubExpression) == counter) ||
+		(step.NodeType == "Assignment" && step.Operator == "+=" && step.LeftHandSide != nil &&
+			baseDecl(step.LeftHandSide) == counter && isOneLiteral(step.RightHandSide))
+	if !increments {
+		return
+	}
+	name := cond.RightExpression.Name
+	savings, note := g.loopSavings(loop, fixedSavings(GasCheckedIncrement))
+	g.addReport(Report{
+		RuleID:        RuleBoundedLoop,
+		Issue:         fmt.Sprintf("'%s' is capped at %s by an earlier require, so the loop counter cannot overflow, yet it is incremented with checked arithmetic on every iteration%s", name, limit, note),
+		Suggestion:    "Drop the step from the for header and end the body with unchecked { ++i; }; arithmetic bounded by the cap can go in unchecked too",
+		GasSavings:    savings.mid(),
+		GasSavingsMin: savings.Min,
+		GasSavingsMax: savings.Max,
+		Location:      loop.Src,
+	})
+}
+
+// requireCap matches a require condition n <= C, n < C, C >= n or C > n,
+// returning n's declaration ID and C's text, or 0
+func requireCap(cond *SolcASTNode, constants map[int]bool) (int, string) {
+	if cond.NodeType != "BinaryOperation" || cond.LeftExpression == nil || cond.RightExpression == nil {
+		return 0, ""
+	}
+	value, limit := cond.LeftExpression, cond.RightExpression
+	switch cond.Operator {
+	case "<", "<=":
+	case ">", ">=":
+		value, limit = limit, value
+	default:
+		return 0, ""
+	}
+	if value.NodeType != "Identifier" || value.ReferencedDecl == 0 {
+		return 0, ""
+	}
+	if _, ok := numberLiteral(limit); ok {
+		return value.ReferencedDecl, limit.Value
+	}
+	if limit.NodeType == "Identifier" && constants[limit.ReferencedDecl] {
+		return value.ReferencedDecl, limit.Name
+	}
+	return 0, ""
+}
+
+// isOneLiteral reports whether node is the number literal 1
+func isOneLiteral(node *SolcASTNode) bool {
+	n, ok := numberLiteral(node)
+	return ok && n == 1
+}
+
+// checkLargeEventData detects emits passing bytes or string values to
+// non-indexed event parameters, which pay LOG data gas per byte. Literals
+// are skipped since their size is known and usually small.
+func (g *GasOptimizer) checkLargeEventData(ast *SolcASTNode) {
+	events := make(map[int]*SolcASTNode)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType == "EventDefinition" && node.Parameters != nil {
+			events[node.ID] = node
+		}
+	})
+	g.walkSolcAST(ast, func(emit *SolcASTNode) {
+		if emit.NodeType != "EmitStatement" || emit.EventCall == nil || emit.EventCall.Expression == nil {
+			return
+		}
+		call := emit.EventCall
+		event, ok := events[call.Expression.ReferencedDecl]
+		if !ok {
+			return
+		}
+		params := event.Parameters.Parameters
+		for i := range call.Arguments {
+			arg := &call.Arguments[i]
+			param := eventParam(params, call.Names, i)
+			if param == nil || param.Indexed || arg.NodeType == "Literal" || !isDynamicBytes(declTypeString(param)) {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleLargeEventData,
+				Issue:      fmt.Sprintf("Event %s logs '%s' as non-indexed %s data, costing %d gas per byte", event.Name, param.Name, declTypeString(param), g.gas.LogDataByte),
+				Suggestion: "If the full data is retrievable elsewhere (calldata, storage, IPFS), emit its keccak256 hash or an indexed key instead",
+				GasSavings: 0,
+				Location:   arg.Src,
+			})
+		}
+	})
+}
+
+// eventParam returns the event parameter receiving argument i, matching by
+// name for {name: value} calls
+func eventParam(params []SolcASTNode, names []string, i int) *SolcASTNode {
+	if len(names) == 0 {
+		if i < len(params) {
+			return &params[i]
+		}
+		return nil
+	}
+	for j := range params {
+		if i < len(names) && params[j].Name == names[i] {
+			return &params[j]
+		}
+	}
+	return nil
+}
+
+// isDynamicBytes reports whether a parameter type string is bytes or string
+func isDynamicBytes(typeString string) bool {
+	base, _, _ := strings.Cut(typeString, " ")
+	return base == "bytes" || base == "string"
+}
+
+// fieldSlot locates a struct field in storage
+type fieldSlot struct {
+	Struct string
+	Slot   int
+}
+
+// structFieldSlots maps the declaration ID of every struct field to its
+// slot within the struct, following solc's packing: fields fill a slot in
+// order until the next does not fit, and reference types take whole slots
+func (g *GasOptimizer) structFieldSlots(ast *SolcASTNode) map[int]fieldSlot {
+	slots := make(map[int]fieldSlot)
+	for _, unit := range g.sourceUnits(ast) {
+		g.walkSolcAST(unit, func(node *SolcASTNode) {
+			if node.NodeType != "StructDefinition" {
+				return
+			}
+			slot, used := 0, 0
+			for i := range node.Members {
+				member := &node.Members[i]
+				size := storageBytes(declTypeString(member))
+				if used+size > 32 {
+					slot, used = slot+1, 0
+				}
+				slots[member.ID] = fieldSlot{node.Name, slot}
+				used += size
+			}
+		})
+	}
+	return slots
+}
+
+// storageBytes returns the bytes a value of the given type takes in a
+// storage slot, 32 for types that always occupy whole slots
+func storageBytes(typeString string) int {
+	switch {
+	case strings.HasPrefix(typeString, "enum "):
+		return 1
+	case strings.HasPrefix(typeString, "contract "), strings.HasPrefix(typeString, "interface "):
+		return 20
+	}
+	return typeBits(typeString) / 8
+}
+
+// checkStructFieldWrites detects runs of consecutive statements assigning
+// fields of one storage struct that share a slot, e.g. s.a = 1; s.b = 2;
+// Each assignment is a separate read-modify-write of the slot unless the
+// optimizer merges them, hence low confidence.
+func (g *GasOptimizer) checkStructFieldWrites(ast *SolcASTNode) {
+	slots := g.structFieldSlots(ast)
+	if len(slots) == 0 {
+		return
+	}
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		if block.NodeType != "Block" {
+			return
+		}
+		type slotKey struct {
+			base string
+			slot fieldSlot
+		}
+		var (
+			run   map[slotKey][]*SolcASTNode
+			order []slotKey
+		)
+		flush := func() {
+			for _, key := range order {
+				if writes := run[key]; len(writes) > 1 {
+					g.reportStructFieldWrites(key.base, writes)
+				}
+			}
+			run, order = make(map[slotKey][]*SolcASTNode), nil
+		}
+		flush()
+		for i := range block.Statements {
+			stmt := &block.Statements[i]
+			assign := stmt.Expression
+			if stmt.NodeType != "ExpressionStatement" || assign == nil || assign.NodeType != "Assignment" ||
+				assign.Operator != "=" || assign.LeftHandSide == nil || assign.LeftHandSide.NodeType != "MemberAccess" {
+				flush()
+				continue
+			}
+			field := assign.LeftHandSide
+			slot, ok := slots[field.ReferencedDecl]
+			base := ""
+			if field.Expression != nil && dataLocation(field.Expression) == "storage" {
+				base = exprKey(field.Expression)
+			}
+			if !ok || base == "" {
+				flush()
+				continue
+			}
+			key := slotKey{base, slot}
+			if _, seen := run[key]; !seen {
+				order = append(order, key)
+			}
+			run[key] = append(run[key], field)
+		}
+		flush()
+	})
+}
+
+// reportStructFieldWrites reports fields of base written separately
+// although they share a storage slot
+func (g *GasOptimizer) reportStructFieldWrites(base string, writes []*SolcASTNode) {
+	fields := make([]string, len(writes))
+	for i, w := range writes {
+		fields[i] = w.MemberName
+	}
+	g.addReport(Report{
+		RuleID:     RuleSplitStructWrite,
+		Issue:      fmt.Sprintf("%s.{%s} share a storage slot but are written by %d separate assignments", base, strings.Join(fields, ", "), len(writes)),
+		Suggestion: "Build the values in a memory struct and assign it once, or assign the struct with a single constructor expression, so the slot is written once",
+		GasSavings: (len(writes) - 1) * g.gas.SloadWarm, // each further write accesses the warm slot
+		Location:   writes[0].Src,
+	})
+}
+
+// checkConversionRoundTrips detects string(bytes(x)) and bytes(string(x))
+// where x already has the outer type, so both conversions can go
+func (g *GasOptimizer) checkConversionRoundTrips(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(outer *SolcASTNode) {
+		to := conversionTarget(outer)
+		if to != "string" && to != "bytes" {
+			return
+		}
+		inner := &outer.Arguments[0]
+		via := conversionTarget(inner)
+		if (via != "string" && via != "bytes") || via == to {
+			return
+		}
+		value := &inner.Arguments[0]
+		base, _, _ := strings.Cut(declTypeString(value), " ")
+		if base == "literal_string" {
+			base = "string"
+		}
+		if base != to {
+			return
+		}
+		name := exprKey(value)
+		if name == "" {
+			name = "the value"
+		}
+		g.addReport(Report{
+			RuleID:     RuleConversionRoundTrip,
+			Issue:      fmt.Sprintf("%s(%s(...)) converts %s to %s and back to its own type", to, via, name, via),
+			Suggestion: fmt.Sprintf("Use %s directly", name),
+			GasSavings: 2 * GasConversion,
+			Location:   outer.Src,
+		})
+	})
+}
+
+// conversionTarget returns the elementary type a one-argument type
+// conversion such as bytes(x) converts to, or ""
+func conversionTarget(call *SolcASTNode) string {
+	if call.NodeType != "FunctionCall" || call.Kind != "typeConversion" || len(call.Arguments) != 1 ||
+		call.Expression == nil || call.Expression.NodeType != "ElementaryTypeNameExpression" || call.Expression.TypeName == nil {
+		return ""
+	}
+	return call.Expression.TypeName.Name
+}
+
+// checkUnusedParameters detects named parameters a function never reads.
+// Functions whose signature is fixed by an override, an interface or a
+// possible override (virtual) are skipped.
+func (g *GasOptimizer) checkUnusedParameters(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil || fn.Parameters == nil ||
+			fn.Virtual || fn.Overrides != nil || len(fn.BaseFunctions) > 0 {
+			return
+		}
+		used := make(map[int]bool)
+		for _, id := range g.referencedDecls(fn.Body) {
+			used[id] = true
+		}
+		// Modifier arguments such as onlyRole(role) are not under the body
+		for i := range fn.Modifiers {
+			for _, id := range g.referencedDecls(&fn.Modifiers[i]) {
+				used[id] = true
+			}
+		}
+		inAssembly := g.assemblyNames(fn.Body)
+		for i := range fn.Parameters.Parameters {
+			param := &fn.Parameters.Parameters[i]
+			if param.Name == "" || used[param.ID] || inAssembly[param.Name] {
+				continue
+			}
+			suggestion := fmt.Sprintf("Remove '%s' from the parameters", param.Name)
+			if fn.Visibility == "external" || fn.Visibility == "public" {
+				suggestion = fmt.Sprintf("Remove '%s', or keep the ABI and leave it unnamed (%s /* %s */)", param.Name, declTypeString(param), param.Name)
+			}
+			g.addReport(Report{
+				RuleID:     RuleUnusedParameter,
+				Issue:      fmt.Sprintf("Parameter '%s' of '%s' is never used", param.Name, fn.Name),
+				Suggestion: suggestion,
+				GasSavings: GasParamDecode,
+				Location:   param.Src,
+			})
+		}
+	})
+}
+
+// checkRepeatedCodeLength detects addr.code.length, or extcodesize(addr) in
+// inline assembly, evaluated more than once for one address in a function.
+// Each is an EXTCODESIZE; addresses reassigned in the function are skipped.
+func (g *GasOptimizer) checkRepeatedCodeLength(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		written := g.writtenDecls(fn.Body)
+		counts := make(map[string]int)
+		first := make(map[string]string)
+		var order []string
+		count := func(key, src string) {
+			if counts[key] == 0 {
+				order = append(order, key)
+				first[key] = src
+			}
+			counts[key]++
+		}
+		g.walkSolcAST(fn.Body, func(node *SolcASTNode) {
+			if node.NodeType == "InlineAssembly" && node.Assembly != nil {
+				walkYul(node.Assembly, func(n *YulNode) {
+					if yulCallName(n) == "extcodesize" && len(n.Arguments) == 1 && n.Arguments[0].NodeType == "YulIdentifier" {
+						count(n.Arguments[0].Name, n.Src)
+					}
+				})
+				return
+			}
+			if node.NodeType != "MemberAccess" || node.MemberName != "length" || node.Expression == nil ||
+				node.Expression.NodeType != "MemberAccess" || node.Expression.MemberName != "code" || node.Expression.Expression == nil {
+				return
+			}
+			addr := node.Expression.Expression
+			if written[baseDecl(addr)] {
+				return
+			}
+			if key := exprKey(addr); key != "" {
+				count(key, node.Src)
+			}
+		})
+		for _, key := range order {
+			if counts[key] < 2 {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleRepeatedCodeLength,
+				Issue:      fmt.Sprintf("Code size of '%s' is checked %d times in '%s', each an EXTCODESIZE", key, counts[key], fn.Name),
+				Suggestion: fmt.Sprintf("Check '%s' once and keep the result in a local bool", key),
+				GasSavings: (counts[key] - 1) * g.gas.Extcodesize,
+				Location:   first[key],
+			})
+		}
+	})
+}
+
+// modularBuiltins maps the inner operator of (a op b) % n to the builtin
+// computing it with 512-bit intermediate precision
+var modularBuiltins = map[string]string{"*": "mulmod", "+": "addmod"}
+
+// checkModularArithmetic detects (a * b) % n and (a + b) % n. mulmod and
+// addmod skip the overflow check and cannot overflow, but they also change
+// behavior: where the product or sum exceeds 256 bits, checked arithmetic
+// reverts and unchecked arithmetic wraps, while the builtins return the
+// exact result. Hence medium confidence.
+func (g *GasOptimizer) checkModularArithmetic(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "BinaryOperation" || node.Operator != "%" || node.LeftExpression == nil || node.RightExpression == nil {
+			return
+		}
+		inner := unparen(node.LeftExpression)
+		builtin, ok := modularBuiltins[inner.Operator]
+		if inner.NodeType != "BinaryOperation" || !ok || !strings.HasPrefix(declTypeString(inner), "uint256") {
+			return
+		}
+		a, b, n := normalizeOperand(inner.LeftExpression), normalizeOperand(inner.RightExpression), normalizeOperand(node.RightExpression)
+		expr := fmt.Sprintf("%s(a, b, n)", builtin)
+		if a != "" && b != "" && n != "" {
+			expr = fmt.Sprintf("%s(%s, %s, %s)", builtin, a, b, n)
+		}
+		g.addReport(Report{
+			RuleID:     RuleModularArithmetic,
+			Issue:      fmt.Sprintf("'(a %s b) %% n' computes a %s b in 256 bits, paying an overflow check (or wrapping in unchecked code)", inner.Operator, inner.Operator),
+			Suggestion: fmt.Sprintf("Use %s, which cannot overflow; check that callers do not rely on the revert", expr),
+			GasSavings: GasConditionCheck,
+			Location:   node.Src,
+		})
+	})
+}
+
+// checkConstructorOnlyWrites detects state variables assigned in a
+// constructor and nowhere else. Value types can be immutable, turning every
+// read into a PUSH; reference types cannot, so they are reported as
+// set-once state to make private and document. Variables that GAS050
+// reports as a stored constructor parameter are left to it, unless GAS050
+// is off or below the minimum confidence.
+func (g *GasOptimizer) checkConstructorOnlyWrites(ast *SolcASTNode) {
+	var params map[int]paramStore
+	if g.Config.reports(RuleConstructorParam) {
+		params = g.constructorParamStores(ast)
+	}
+	for _, v := range g.constructorOnlyVars(ast) {
+		if _, ok := params[v.ID]; ok {
+			continue
+		}
+		if dataLocation(v) == "storage" {
+			g.addReport(Report{
+				RuleID:     RuleConstructorOnly,
+				Issue:      fmt.Sprintf("'%s' is only assigned in the constructor; as a %s it cannot be immutable", v.Name, declTypeString(v)),
+				Suggestion: fmt.Sprintf("Make '%s' private with a getter if needed, and document it as set once at deployment", v.Name),
+				GasSavings: 0,
+				Location:   v.Src,
+			})
+			continue
+		}
+		g.addReport(Report{
+			RuleID:        RuleConstructorOnly,
+			Issue:         fmt.Sprintf("'%s' is only assigned in the constructor but every read pays an SLOAD", v.Name),
+			Suggestion:    fmt.Sprintf("Declare '%s' immutable", v.Name),
+			GasSavings:    (g.gas.SloadWarm + g.gas.SloadCold) / 2, // per read, warm or cold
+			GasSavingsMin: g.gas.SloadWarm,
+			GasSavingsMax: g.gas.SloadCold,
+			Location:      v.Src,
+		})
+	}
+}
+
+// constructorOnlyVars lists the state variables written by the
+// constructor of the contract declaring them and by no other function,
+// modifier or constructor; a variable a derived contract's constructor
+// sets could not be immutable. Sources with inline assembly outside
+// constructors yield none, since sstore could write any slot.
+func (g *GasOptimizer) constructorOnlyVars(ast *SolcASTNode) []*SolcASTNode {
+	var vars []*SolcASTNode
+	declaredIn := make(map[int]int) // variable ID -> contract ID
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "ContractDefinition" {
+			return
+		}
+		for i := range node.Nodes {
+			if member := &node.Nodes[i]; isStorageVariable(member) {
+				vars = append(vars, member)
+				declaredIn[member.ID] = node.ID
+			}
+		}
+	})
+	if len(vars) == 0 {
+		return nil
+	}
+	inConstructor, elsewhere := make(map[int]bool), make(map[int]bool)
+	assembly := false
+	g.walkSolcAST(ast, func(contract *SolcASTNode) {
+		if contract.NodeType != "ContractDefinition" {
+			return
+		}
+		for i := range contract.Nodes {
+			fn := &contract.Nodes[i]
+			if (fn.NodeType != "FunctionDefinition" && fn.NodeType != "ModifierDefinition") || fn.Body == nil {
+				continue
+			}
+			if fn.Kind != "constructor" {
+				g.walkSolcAST(fn.Body, func(n *SolcASTNode) {
+					assembly = assembly || n.NodeType == "InlineAssembly"
+				})
+			}
+			for id := range g.storageWrites(fn.Body) {
+				if fn.Kind == "constructor" && declaredIn[id] == contract.ID {
+					inConstructor[id] = true
+				} else {
+					elsewhere[id] = true
+				}
+			}
+		}
+	})
+	if assembly {
+		return nil
+	}
+	only := vars[:0]
+	for _, v := range vars {
+		if inConstructor[v.ID] && !elsewhere[v.ID] {
+			only = append(only, v)
+		}
+	}
+	return only
+}
+
+// checkConstructorParams detects value-type state variables that their
+// contract's constructor sets from one of its parameters, in a single
+// unconditional assignment, and that nothing else writes: the textbook
+// immutable. A more certain case of GAS035.
+func (g *GasOptimizer) checkConstructorParams(ast *SolcASTNode) {
+	stores := g.constructorParamStores(ast)
+	for _, v := range g.constructorOnlyVars(ast) {
+		store, ok := stores[v.ID]
+		if !ok {
+			continue
+		}
+		g.addReport(Report{
+			RuleID:        RuleConstructorParam,
+			Issue:         fmt.Sprintf("'%s' is set once from constructor parameter '%s' and never written again, but every read pays an SLOAD", v.Name, store.param.Name),
+			Suggestion:    fmt.Sprintf("Declare '%s' immutable", v.Name),
+			GasSavings:    (g.gas.SloadWarm + g.gas.SloadCold) / 2, // per read, warm or cold
+			GasSavingsMin: g.gas.SloadWarm,
+			GasSavingsMax: g.gas.SloadCold,
+			Location:      v.Src,
+		})
+	}
+}
+
+// paramStore is a top-level constructor statement var = param
+type paramStore struct {
+	assignment *SolcASTNode
+	param      *SolcASTNode
+}
+
+// constructorParamStores maps value-type state variables to the statement
+// of their contract's constructor that assigns them a constructor
+// parameter, where that is the constructor's only write to them. Below
+// Solidity 0.8.21 immutables cannot be read during construction, so
+// variables the constructor also reads are left out there.
+func (g *GasOptimizer) constructorParamStores(ast *SolcASTNode) map[int]paramStore {
+	min, ok := g.pragmaMinVersion(ast)
+	readable := ok && min.atLeast(solcVersion{0, 8, 21})
+	stores := make(map[int]paramStore)
+	g.walkSolcAST(ast, func(contract *SolcASTNode) {
+		if contract.NodeType != "ContractDefinition" {
+			return
+		}
+		values := make(map[int]bool)
+		var ctor *SolcASTNode
+		for i := range contract.Nodes {
+			switch member := &contract.Nodes[i]; {
+			case isStorageVariable(member) && dataLocation(member) != "storage":
+				values[member.ID] = true
+			case member.NodeType == "FunctionDefinition" && member.Kind == "constructor":
+				ctor = member
+			}
+		}
+		if ctor == nil || ctor.Body == nil || ctor.Parameters == nil {
+			return
+		}
+		params := make(map[int]*SolcASTNode)
+		for i := range ctor.Parameters.Parameters {
+			params[ctor.Parameters.Parameters[i].ID] = &ctor.Parameters.Parameters[i]
+		}
+		found := make(map[int]paramStore)
+		for i := range ctor.Body.Statements {
+			stmt := &ctor.Body.Statements[i]
+			if stmt.NodeType != "ExpressionStatement" || stmt.Expression == nil {
+				continue
+			}
+			a := stmt.Expression
+			if a.NodeType != "Assignment" || a.Operator != "=" || a.LeftHandSide == nil || a.RightHandSide == nil ||
+				a.LeftHandSide.NodeType != "Identifier" || a.RightHandSide.NodeType != "Identifier" {
+				continue
+			}
+			if param := params[a.RightHandSide.ReferencedDecl]; param != nil && values[a.LeftHandSide.ReferencedDecl] {
+				found[a.LeftHandSide.ReferencedDecl] = paramStore{a, param}
+			}
+		}
+		if len(found) == 0 {
+			return
+		}
+		// Any other write, or a read where immutables cannot be read,
+		// disqualifies the variable
+		writes, refs := make(map[int]int), make(map[int]int)
+		g.walkSolcAST(ctor.Body, func(n *SolcASTNode) {
+			switch n.NodeType {
+			case "Assignment":
+				if n.LeftHandSide != nil {
+					writes[baseDecl(n.LeftHandSide)]++
+				}
+			case "UnaryOperation":
+				if (n.Operator == "++" || n.Operator == "--" || n.Operator == "delete") && n.SubExpression != nil {
+					writes[baseDecl(n.SubExpression)]++
+				}
+			case "Identifier":
+				refs[n.ReferencedDecl]++
+			}
+		})
+		for id, store := range found {
+			if writes[id] == 1 && (readable || refs[id] == 1) {
+				stores[id] = store
+			}
+		}
+	})
+	return stores
+}
+
+// storageWrites collects the declarations written under node, counting
+// anything that may write through an alias: storage pointers initialized
+// from a variable, storage arguments, delete and push/pop
+func (g *GasOptimizer) storageWrites(node *SolcASTNode) map[int]bool {
+	written := g.writtenDecls(node)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		switch n.NodeType {
+		case "UnaryOperation":
+			if n.Operator == "delete" && n.SubExpression != nil {
+				written[baseDecl(n.SubExpression)] = true
+			}
+		case "VariableDeclarationStatement":
+			for _, decl := range n.Declarations {
+				if decl.StorageLocation == "storage" && n.InitialValue != nil {
+					for _, id := range g.referencedDecls(n.InitialValue) {
+						written[id] = true
+					}
+				}
+			}
+		case "FunctionCall":
+			if n.Expression != nil && n.Expression.NodeType == "MemberAccess" && n.Expression.Expression != nil &&
+				(n.Expression.MemberName == "push" || n.Expression.MemberName == "pop") {
+				written[baseDecl(n.Expression.Expression)] = true
+			}
+			for i := range n.Arguments {
+				if dataLocation(&n.Arguments[i]) == "storage" {
+					written[baseDecl(&n.Arguments[i])] = true
+				}
+			}
+		}
+	})
+	return written
+}
+
+// storedExpr is an expression assigned to a state variable that has not
+// been invalidated by a later write to it or to one of its operands
+type storedExpr struct {
+	target   string
+	targetID int
+	reads    []int
+}
+
+// checkRecomputedReturns detects x = expr; ... return expr; where x is a
+// state variable and nothing in between changes x or expr's operands. The
+// return evaluates expr a second time, operand SLOADs included.
+func (g *GasOptimizer) checkRecomputedReturns(ast *SolcASTNode) {
+	state := g.stateVariables(ast)
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		if block.NodeType != "Block" && block.NodeType != "UncheckedBlock" {
+			return
+		}
+		stored := make(map[string]storedExpr)
+		for i := range block.Statements {
+			stmt := &block.Statements[i]
+			if stmt.NodeType == "Return" && stmt.Expression != nil {
+				expr := normalizeExpr(unparen(stmt.Expression))
+				if s, ok := stored[expr]; ok {
+					g.reportRecomputedReturn(stmt, expr, s, state)
+				}
+			}
+			g.invalidateStored(stmt, stored)
+			if stmt.NodeType != "ExpressionStatement" || stmt.Expression == nil {
+				continue
+			}
+			assign := stmt.Expression
+			if assign.NodeType != "Assignment" || assign.Operator != "=" || assign.LeftHandSide == nil || assign.RightHandSide == nil {
+				continue
+			}
+			target, rhs := baseDecl(assign.LeftHandSide), unparen(assign.RightHandSide)
+			key, expr := indexKey(assign.LeftHandSide), normalizeExpr(rhs)
+			if !state[target] || key == "" || expr == "" || rhs.NodeType != "BinaryOperation" {
+				continue
+			}
+			reads := g.referencedDecls(rhs)
+			if !slices.Contains(reads, target) {
+				stored[expr] = storedExpr{target: key, targetID: target, reads: reads}
+			}
+		}
+	})
+}
+
+// invalidateStored drops stored expressions whose target or operands stmt
+// may change. Calls and assembly may write any state.
+func (g *GasOptimizer) invalidateStored(stmt *SolcASTNode, stored map[string]storedExpr) {
+	opaque := false
+	g.walkSolcAST(stmt, func(n *SolcASTNode) {
+		opaque = opaque || n.NodeType == "InlineAssembly" || (n.NodeType == "FunctionCall" && n.Kind == "functionCall")
+	})
+	if opaque {
+		clear(stored)
+		return
+	}
+	written := g.storageWrites(stmt)
+	for expr, s := range stored {
+		if written[s.targetID] {
+			delete(stored, expr)
+			continue
+		}
+		for _, id := range s.reads {
+			if written[id] {
+				delete(stored, expr)
+				break
+			}
+		}
+	}
+}
+
+// reportRecomputedReturn reports a return of a stored expression; the
+// saving is its arithmetic plus a warm SLOAD per state operand
+func (g *GasOptimizer) reportRecomputedReturn(ret *SolcASTNode, expr string, s storedExpr, state map[int]bool) {
+	savings := 0
+	g.walkSolcAST(ret.Expression, func(n *SolcASTNode) {
+		if n.NodeType == "BinaryOperation" {
+			savings += GasCheckedOp
+		}
+	})
+	for _, id := range s.reads {
+		if state[id] {
+			savings += g.gas.SloadWarm
+		}
+	}
+	g.addReport(Report{
+		RuleID:     RuleRecomputedReturn,
+		Issue:      fmt.Sprintf("'%s' is stored to '%s' and then computed again for the return", expr, s.target),
+		Suggestion: fmt.Sprintf("Compute '%s' once into a local variable, assign it to '%s' and return the local", expr, s.target),
+		GasSavings: savings,
+		Location:   ret.Src,
+	})
+}
+
+// checkLengthBeforeIndex detects require(i < arr.length) followed by a
+// statement indexing arr[i], where arr is a dynamic storage array. The
+// index access loads the length again for its own bounds check.
+func (g *GasOptimizer) checkLengthBeforeIndex(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		for i := 0; i+1 < len(block.Statements); i++ {
+			stmt := &block.Statements[i]
+			if stmt.NodeType != "ExpressionStatement" || stmt.Expression == nil || !isRequireCall(stmt.Expression) {
+				continue
+			}
+			arr, idx := boundsCheck(unparen(&stmt.Expression.Arguments[0]))
+			if arr == "" || idx == "" {
+				continue
+			}
+			indexed := false
+			g.walkSolcAST(&block.Statements[i+1], func(n *SolcASTNode) {
+				indexed = indexed || (n.NodeType == "IndexAccess" && n.BaseExpression != nil && n.IndexExpression != nil &&
+					indexKey(n.BaseExpression) == arr && indexKey(n.IndexExpression) == idx)
+			})
+			if !indexed {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleLengthBeforeIndex,
+				Issue:      fmt.Sprintf("require(%s < %s.length) reads the length, then %s[%s] reads it again for its bounds check", idx, arr, arr, idx),
+				Suggestion: fmt.Sprintf("Drop the require if a Panic revert is acceptable, or cache %s.length in a local if it is used again", arr),
+				GasSavings: g.gas.SloadWarm,
+				Location:   stmt.Src,
+			})
+		}
+	})
+}
+
+// boundsCheck returns the array and index of i < arr.length or
+// arr.length > i, where arr is a dynamic storage array, or ""
+func boundsCheck(cond *SolcASTNode) (arr, idx string) {
+	if cond.NodeType != "BinaryOperation" || cond.LeftExpression == nil || cond.RightExpression == nil {
+		return "", ""
+	}
+	index, length := cond.LeftExpression, cond.RightExpression
+	switch cond.Operator {
+	case ">":
+		index, length = length, index
+	case "<":
+	default:
+		return "", ""
+	}
+	length = unparen(length)
+	if length.NodeType != "MemberAccess" || length.MemberName != "length" || length.Expression == nil ||
+		!isStorageArray(length.Expression) {
+		return "", ""
+	}
+	if id := length.Expression.TypeDescriptions.TypeIdentifier; !strings.HasSuffix(id, "$dyn_storage") && !strings.HasSuffix(id, "$dyn_storage_ptr") {
+		return "", "" // a fixed-size array's length is a constant
+	}
+	return indexKey(length.Expression), indexKey(unparen(index))
+}
+
+// checkEmitBeforeWrite detects an emit whose arguments read a state
+// variable that a later statement of the same block writes, so the event
+// logs the value from before the update. Writes whose new value the event
+// also carries, as in emit OwnerChanged(owner, newOwner); owner = newOwner;,
+// log a deliberate old/new pair and are skipped.
+func (g *GasOptimizer) checkEmitBeforeWrite(ast *SolcASTNode) {
+	state := g.stateVariables(ast)
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		for i := range block.Statements {
+			emit := &block.Statements[i]
+			if emit.NodeType != "EmitStatement" || emit.EventCall == nil {
+				continue
+			}
+			logged := make(map[int]bool)
+			for j := range emit.EventCall.Arguments {
+				for _, id := range g.referencedDecls(&emit.EventCall.Arguments[j]) {
+					logged[id] = true
+				}
+			}
+			for j := i + 1; j < len(block.Statements); j++ {
+				if write := g.staleWrite(&block.Statements[j], logged, state); write != nil {
+					name := exprKey(write)
+					if name == "" {
+						name = "a logged state variable"
+					}
+					g.addReport(Report{
+						RuleID:     RuleEmitBeforeWrite,
+						Issue:      fmt.Sprintf("Event is emitted before '%s' is updated, so it logs the old value", name),
+						Suggestion: fmt.Sprintf("Emit the event after the write to '%s' (checks-effects-interactions), or pass the new value explicitly", name),
+						GasSavings: 0,
+						Location:   emit.Src,
+					})
+					break
+				}
+			}
+		}
+	})
+}
+
+// staleWrite returns the target of the first write under stmt to a logged
+// state variable whose new value is not itself logged, or nil
+func (g *GasOptimizer) staleWrite(stmt *SolcASTNode, logged, state map[int]bool) *SolcASTNode {
+	var target *SolcASTNode
+	g.inspectSolcAST(stmt, func(n *SolcASTNode) bool {
+		if target != nil {
+			return false
+		}
+		var lhs *SolcASTNode
+		switch {
+		case n.NodeType == "Assignment" && n.LeftHandSide != nil:
+			lhs = n.LeftHandSide
+			for _, id := range g.referencedDecls(n.RightHandSide) {
+				if logged[id] && id != baseDecl(lhs) {
+					return true // the event carries the new value
+				}
+			}
+		case n.NodeType == "UnaryOperation" && (n.Operator == "++" || n.Operator == "--" || n.Operator == "delete") && n.SubExpression != nil:
+			lhs = n.SubExpression
+		default:
+			return true
+		}
+		if id := baseDecl(lhs); state[id] && logged[id] {
+			target = lhs
+		}
+		return true
+	})
+	return target
+}
+
+// signedIntType matches the signed integer types int and int8 to int256
+var signedIntType = regexp.MustCompile(`^int\d*$`)
+
+// checkNonNegativeInts detects signed integer state and local variables
+// that are only initialized, assigned and compared with non-negative
+// literals, only incremented, and never negated. Other reads are not
+// traced, hence low confidence.
+func (g *GasOptimizer) checkNonNegativeInts(ast *SolcASTNode) {
+	candidates := make(map[int]*SolcASTNode)
+	var order []int
+	consider := func(decl *SolcASTNode, init *SolcASTNode) {
+		if decl.ID != 0 && !decl.Constant && signedIntType.MatchString(declTypeString(decl)) &&
+			(init == nil || isNonNegativeLiteral(init)) {
+			candidates[decl.ID] = decl
+			order = append(order, decl.ID)
+		}
+	}
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		switch node.NodeType {
+		case "ContractDefinition":
+			for i := range node.Nodes {
+				if member := &node.Nodes[i]; member.NodeType == "VariableDeclaration" {
+					consider(member, member.InitialValue)
+				}
+			}
+		case "VariableDeclarationStatement":
+			if len(node.Declarations) == 1 {
+				consider(&node.Declarations[0], node.InitialValue)
+			}
+		}
+	})
+	if len(candidates) == 0 {
+		return
+	}
+	signed := make(map[int]bool)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		switch node.NodeType {
+		case "Assignment":
+			if node.LeftHandSide == nil {
+				return
+			}
+			if id := node.LeftHandSide.ReferencedDecl; node.LeftHandSide.NodeType == "Identifier" && candidates[id] != nil {
+				if (node.Operator != "=" && node.Operator != "+=") || node.RightHandSide == nil || !isNonNegativeLiteral(node.RightHandSide) {
+					signed[id] = true
+				}
+			}
+			for _, c := range node.LeftHandSide.Components {
+				if c != nil && c.NodeType == "Identifier" {
+					signed[c.ReferencedDecl] = true // assigned from a tuple
+				}
+			}
+		case "UnaryOperation":
+			if sub := node.SubExpression; sub != nil && sub.NodeType == "Identifier" && node.Operator != "++" && node.Operator != "delete" {
+				signed[sub.ReferencedDecl] = true
+			}
+		case "BinaryOperation":
+			if !comparisonOps[node.Operator] || node.LeftExpression == nil || node.RightExpression == nil {
+				return
+			}
+			left, right := unparen(node.LeftExpression), unparen(node.RightExpression)
+			if left.NodeType == "Identifier" && !isNonNegativeLiteral(right) {
+				signed[left.ReferencedDecl] = true
+			}
+			if right.NodeType == "Identifier" && !isNonNegativeLiteral(left) {
+				signed[right.ReferencedDecl] = true
+			}
+		case "InlineAssembly":
+			clear(candidates) // assembly may write anything
+		}
+	})
+	for _, id := range order {
+		decl := candidates[id]
+		if decl == nil || signed[id] {
+			continue
+		}
+		typ := declTypeString(decl)
+		g.addReport(Report{
+			RuleID:     RuleNonNegativeInt,
+			Issue:      fmt.Sprintf("'%s' is declared %s but only ever holds and is compared with non-negative values", decl.Name, typ),
+			Suggestion: fmt.Sprintf("Declare '%s' as u%s if it can never be negative", decl.Name, typ),
+			GasSavings: GasSignedCheck,
+			Location:   decl.Src,
+		})
+	}
+}
+
+// comparisonOps are the binary operators comparing two values
+var comparisonOps = map[string]bool{"<": true, ">": true, "<=": true, ">=": true, "==": true, "!=": true}
+
+// isNonNegativeLiteral reports whether node is a number literal; negative
+// numbers are a unary minus applied to a literal
+func isNonNegativeLiteral(node *SolcASTNode) bool {
+	node = unparen(node)
+	return node.NodeType == "Literal" && node.Kind == "number"
+}
+
+// checkUnsignedZeroComparisons detects x >= 0 and x < 0 (or 0 <= x and
+// 0 > x) on unsigned x, which are always true and always false
+func (g *GasOptimizer) checkUnsignedZeroComparisons(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "BinaryOperation" || node.LeftExpression == nil || node.RightExpression == nil {
+			return
+		}
+		value, op := unparen(node.LeftExpression), node.Operator
+		if isZeroLiteral(value) {
+			// Mirror 0 <= x into x >= 0 and 0 > x into x < 0
+			value, op = unparen(node.RightExpression), map[string]string{"<=": ">=", ">": "<"}[op]
+		} else if !isZeroLiteral(unparen(node.RightExpression)) {
+			return
+		}
+		var always string
+		switch op {
+		case ">=":
+			always = "true"
+		case "<":
+			always = "false"
+		default:
+			return
+		}
+		typ := declTypeString(value)
+		if !strings.HasPrefix(typ, "uint") {
+			return
+		}
+		name := exprKey(value)
+		if name == "" {
+			name = "the value"
+		}
+		g.addReport(Report{
+			RuleID:     RuleUnsignedZeroCompare,
+			Issue:      fmt.Sprintf("'%s %s 0' is always %s because %s is %s", name, op, always, name, typ),
+			Suggestion: "Remove the comparison, or fix it if a stricter check such as > 0 was intended",
+			GasSavings: GasConditionCheck,
+			Location:   node.Src,
+		})
+	})
+}
+
+// checkSharedPreambles detects public and external functions of one
+// contract that begin with the same sequence of require or assert checks,
+// each copy of which is compiled into the bytecode
+func (g *GasOptimizer) checkSharedPreambles(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(contract *SolcASTNode) {
+		if contract.NodeType != "ContractDefinition" {
+			return
+		}
+		groups := make(map[string][]*SolcASTNode)
+		var keys []string
+		lengths := make(map[string]int)
+		for i := range contract.Nodes {
+			fn := &contract.Nodes[i]
+			if fn.NodeType != "FunctionDefinition" || fn.Body == nil || (fn.Visibility != "public" && fn.Visibility != "external") {
+				continue
+			}
+			checks := preamble(fn.Body)
+			if len(checks) == 0 {
+				continue
+			}
+			key := strings.Join(checks, "; ")
+			if _, seen := groups[key]; !seen {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], fn)
+			lengths[key] = len(checks)
+		}
+		for _, key := range keys {
+			fns := groups[key]
+			if len(fns) < 2 {
+				continue
+			}
+			names := make([]string, len(fns))
+			for i, fn := range fns {
+				names[i] = fn.Name
+			}
+			g.addReport(Report{
+				RuleID:     RuleSharedPreamble,
+				Issue:      fmt.Sprintf("Functions %s all start with the same checks: %s", strings.Join(names, ", "), key),
+				Suggestion: "Move the checks into one internal function called by each, or combine the functions if they are always called together",
+				GasSavings: (len(fns) - 1) * lengths[key] * GasInlinedStatement,
+				Location:   fns[0].Src,
+			})
+		}
+	})
+}
+
+// preamble renders the leading require and assert calls of a function
+// body, stopping at the first other statement or unrenderable argument
+func preamble(body *SolcASTNode) []string {
+	var checks []string
+	for i := range body.Statements {
+		stmt := &body.Statements[i]
+		if stmt.NodeType != "ExpressionStatement" || stmt.Expression == nil || !isRequireCall(stmt.Expression) {
+			break
+		}
+		call := stmt.Expression
+		args := make([]string, len(call.Arguments))
+		for j := range call.Arguments {
+			if args[j] = checkKey(&call.Arguments[j]); args[j] == "" {
+				return checks
+			}
+		}
+		checks = append(checks, call.Expression.Name+"("+strings.Join(args, ", ")+")")
+	}
+	return checks
+}
+
+// checkKey renders a require argument: a comparison, a negation, a call or
+// a literal such as the revert string
+func checkKey(node *SolcASTNode) string {
+	node = unparen(node)
+	switch {
+	case node.NodeType == "UnaryOperation" && node.Operator == "!" && node.SubExpression != nil:
+		if sub := checkKey(node.SubExpression); sub != "" {
+			return "!" + sub
+		}
+		return ""
+	case node.NodeType == "Literal" && node.Kind == "string":
+		return strconv.Quote(node.Value)
+	case node.NodeType == "BinaryOperation":
+		return normalizeExpr(node)
+	}
+	return exprKey(node)
+}
+
+// checkRepeatedDecodes detects abi.decode of the same payload into the same
+// types more than once in a function
+func (g *GasOptimizer) checkRepeatedDecodes(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil {
+			return
+		}
+		written := g.writtenDecls(fn.Body)
+		counts := make(map[string]int)
+		types := make(map[string]int)
+		var order []string
+		g.walkSolcAST(fn.Body, func(call *SolcASTNode) {
+			if call.NodeType != "FunctionCall" || call.Expression == nil || call.Expression.NodeType != "MemberAccess" {
+				return
+			}
+			callee := call.Expression
+			if callee.MemberName != "decode" || callee.Expression == nil || callee.Expression.Name != "abi" || len(call.Arguments) != 2 {
+				return
+			}
+			data := exprKey(&call.Arguments[0])
+			decoded := decodeTypes(&call.Arguments[1])
+			if data == "" || len(decoded) == 0 {
+				return
+			}
+			for _, ref := range g.referencedDecls(&call.Arguments[0]) {
+				if written[ref] {
+					return // payload changes within the function
+				}
+			}
+			key := fmt.Sprintf("abi.decode(%s, (%s))", data, strings.Join(decoded, ", "))
+			if counts[key] == 0 {
+				order = append(order, key)
+				types[key] = len(decoded)
+			}
+			counts[key]++
+		})
+		for _, key := range order {
+			count := counts[key]
+			if count < 2 {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleRepeatedDecode,
+				Issue:      fmt.Sprintf("'%s' runs %d times in '%s'", key, count, fn.Name),
+				Suggestion: "Decode once into local variables and reuse them",
+				GasSavings: (count - 1) * (types[key]*GasParamDecode + GasMemoryAlloc),
+				Location:   fn.Src,
+			})
+		}
+	})
+}
+
+// decodeTypes returns the type names of an abi.decode type list such as
+// (address, uint256), read from the type(...) of each component
+func decodeTypes(node *SolcASTNode) []string {
+	components := []*SolcASTNode{node}
+	if node.NodeType == "TupleExpression" {
+		components = node.Components
+	}
+	names := make([]string, 0, len(components))
+	for _, c := range components {
+		if c == nil || c.TypeDescriptions == nil {
+			return nil
+		}
+		name, ok := strings.CutPrefix(c.TypeDescriptions.TypeString, "type(")
+		if !ok {
+			return nil
+		}
+		names = append(names, strings.TrimSuffix(name, ")"))
+	}
+	return names
+}
+
+// checkGasStipends detects calls forwarding a literal amount of gas through
+// the {gas: ...} call option
+func (g *GasOptimizer) checkGasStipends(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "FunctionCallOptions" || node.Expression == nil {
+			return
+		}
+		for i, name := range node.Names {
+			if name != "gas" || i >= len(node.Options) {
+				continue
+			}
+			gas := unparen(&node.Options[i])
+			if gas.NodeType != "Literal" || gas.Kind != "number" {
+				continue
+			}
+			callee := exprKey(node.Expression)
+			if callee == "" {
+				callee = "call"
+			}
+			g.addReport(Report{
+				RuleID:     RuleGasStipend,
+				Issue:      fmt.Sprintf("'%s' forwards a hardcoded %s gas, which may stop being enough after a gas repricing", callee, gas.Value),
+				Suggestion: "Drop the gas option and guard against reentrancy instead, or document why this stipend is required",
+				GasSavings: 0,
+				Location:   node.Src,
+			})
+		}
+	})
+}
+
+// checkStoragePointers detects local storage pointers to an indexed element,
+// such as Position storage p = positions[id], that the rest of the block
+// never uses or bypasses by indexing positions[id] again
+func (g *GasOptimizer) checkStoragePointers(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(block *SolcASTNode) {
+		for i := range block.Statements {
+			stmt := &block.Statements[i]
+			if stmt.NodeType != "VariableDeclarationStatement" || len(stmt.Declarations) != 1 || stmt.InitialValue == nil {
+				continue
+			}
+			decl := &stmt.Declarations[0]
+			target := unparen(stmt.InitialValue)
+			key := indexKey(target)
+			if decl.StorageLocation != "storage" || decl.ID == 0 || !strings.Contains(key, "[") {
+				continue
+			}
+			rest := &SolcASTNode{NodeType: "Block", Statements: block.Statements[i+1:]}
+			uses := 0
+			for _, id := range g.referencedDecls(rest) {
+				if id == decl.ID {
+					uses++
+				}
+			}
+			if g.assemblyNames(rest)[decl.Name] {
+				uses++ // p.slot in assembly without externalReferences
+			}
+			indexChanged := false
+			written := g.writtenDecls(rest)
+			for _, id := range g.referencedDecls(target) {
+				if id != baseDecl(target) && written[id] {
+					indexChanged = true // the direct accesses may reach another element
+				}
+			}
+			direct := 0
+			if !indexChanged {
+				g.inspectSolcAST(rest, func(n *SolcASTNode) bool {
+					if n.NodeType == "IndexAccess" && indexKey(n) == key {
+						direct++
+						return false
+					}
+					return true
+				})
+			}
+			perSlot := g.slotCost(target)
+			switch {
+			case uses == 0:
+				g.addReport(Report{
+					RuleID:     RuleStoragePointer,
+					Issue:      fmt.Sprintf("Storage pointer '%s' to '%s' is never used", decl.Name, key),
+					Suggestion: fmt.Sprintf("Remove '%s', or use it in place of the direct accesses to '%s'", decl.Name, key),
+					GasSavings: perSlot,
+					Location:   stmt.Src,
+				})
+			case direct > 0:
+				g.addReport(Report{
+					RuleID:     RuleStoragePointer,
+					Issue:      fmt.Sprintf("'%s' is indexed directly %d times although storage pointer '%s' points at it", key, direct, decl.Name),
+					Suggestion: fmt.Sprintf("Access the element through '%s' so its slot is computed once", decl.Name),
+					GasSavings: direct * perSlot,
+					Location:   stmt.Src,
+				})
+			}
+		}
+	})
+}
+
+// slotCost estimates computing the storage slot of an element such as
+// m[k].items[i]: a keccak256 of key and slot per mapping level and a bounds
+// check per array level
+func (g *GasOptimizer) slotCost(node *SolcASTNode) int {
+	cost := 0
+	for node != nil {
+		switch node.NodeType {
+		case "IndexAccess":
+			if strings.HasPrefix(declTypeString(node.BaseExpression), "mapping(") {
+				cost += g.gas.Keccak + 2*g.gas.KeccakWord
+			} else {
+				cost += GasIndexAccess
+			}
+			node = node.BaseExpression
+		case "MemberAccess":
+			node = node.Expression
+		default:
+			node = nil
+		}
+	}
+	return cost
+}
+
+// arithBound records why a local cannot overflow when incremented (up) or
+// underflow when decremented (down)
+type arithBound struct {
+	up, down bool
+	why      string
+}
+
+// checkUncheckedArithmetic detects checked increments and decrements by one
+// of unsigned locals that a preceding require or the enclosing for loop's
+// condition already keeps in range, e.g. require(n < 10); n++; or arr[i + 1]
+// inside for (i = 0; i < len; ...). Loop counter steps are left to
+// checkBoundedLoops.
+func (g *GasOptimizer) checkUncheckedArithmetic(ast *SolcASTNode) {
+	min, ok := g.pragmaMinVersion(ast)
+	if !ok || !min.atLeast(solcVersion{0, 8, 0}) {
+		return
+	}
+	constants := g.constantDecls(ast)
+	state := g.stateVariables(ast)
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		switch node.NodeType {
+		case "Block":
+			bounds := make(map[int]arithBound)
+			for i := range node.Statements {
+				stmt := &node.Statements[i]
+				if stmt.NodeType == "ExpressionStatement" && stmt.Expression != nil && isRequireCall(stmt.Expression) {
+					cond := &stmt.Expression.Arguments[0]
+					if id, b := requireBound(cond, constants); id != 0 && !state[id] {
+						b.up, b.down = b.up || bounds[id].up, b.down || bounds[id].down
+						bounds[id] = b
+					}
+					continue
+				}
+				if len(bounds) > 0 {
+					g.reportSafeArithmetic(stmt, bounds)
+				}
+				for id := range g.writtenDecls(stmt) {
+					delete(bounds, id)
+				}
+			}
+		case "ForStatement":
+			cond := node.Condition
+			if cond == nil || cond.NodeType != "BinaryOperation" || cond.Operator != "<" || node.Body == nil ||
+				cond.LeftExpression == nil || cond.RightExpression == nil || cond.LeftExpression.NodeType != "Identifier" {
+				return
+			}
+			counter := cond.LeftExpression.ReferencedDecl
+			if counter == 0 || state[counter] || g.writtenDecls(node.Body)[counter] ||
+				declTypeString(cond.LeftExpression) != declTypeString(cond.RightExpression) {
+				return
+			}
+			why := "the loop condition " + normalizeExpr(cond)
+			g.reportSafeArithmetic(node.Body, map[int]arithBound{counter: {up: true, why: why}})
+		}
+	})
+}
+
+// requireBound matches require conditions that keep an unsigned local x in
+// range: x < C or x <= C for a literal or constant C below the type's
+// maximum, and x > 0, x != 0 or x >= 1. It returns x's declaration ID, or 0.
+func requireBound(cond *SolcASTNode, constants map[int]bool) (int, arithBound) {
+	cond = unparen(cond)
+	if cond.NodeType != "BinaryOperation" || cond.LeftExpression == nil || cond.RightExpression == nil {
+		return 0, arithBound{}
+	}
+	value, limit, op := cond.LeftExpression, cond.RightExpression, cond.Operator
+	if value.NodeType != "Identifier" {
+		value, limit = limit, value
+		op = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "!=": "!="}[op]
+	}
+	typeString := declTypeString(value)
+	if value.NodeType != "Identifier" || value.ReferencedDecl == 0 || !strings.HasPrefix(typeString, "uint") {
+		return 0, arithBound{}
+	}
+	b := arithBound{why: "require(" + normalizeExpr(cond) + ")"}
+	n, literal := numberLiteral(limit)
+	constant := limit.NodeType == "Identifier" && constants[limit.ReferencedDecl]
+	switch {
+	case op == "<" && (literal || constant):
+		b.up = true
+	case op == "<=" && literal && (typeBits(typeString) >= 63 || n < 1<<typeBits(typeString)-1):
+		b.up = true
+	case (op == ">" || op == "!=") && literal && n == 0, op == ">=" && literal && n == 1:
+		b.down = true
+	default:
+		return 0, arithBound{}
+	}
+	return value.ReferencedDecl, b
+}
+
+// reportSafeArithmetic reports x++, x--, x += 1, x -= 1, x + 1 and x - 1
+// under node for bounded locals x, skipping unchecked blocks and loops
+// that write x. An increment or decrement uses up the bound, so only the
+// first is reported.
+func (g *GasOptimizer) reportSafeArithmetic(node *SolcASTNode, bounds map[int]arithBound) {
+	used := make(map[int]bool)
+	g.inspectSolcAST(node, func(n *SolcASTNode) bool {
+		var operand *SolcASTNode
+		var expr string
+		up, writes := false, true
+		switch {
+		case n.NodeType == "UncheckedBlock":
+			return false
+		case n.NodeType == "ForStatement" || n.NodeType == "WhileStatement" || n.NodeType == "DoWhileStatement":
+			for id := range g.writtenDecls(n) {
+				if _, ok := bounds[id]; ok {
+					return false // repeated steps can leave the range
+				}
+			}
+			return true
+		case n.NodeType == "UnaryOperation" && (n.Operator == "++" || n.Operator == "--") && n.SubExpression != nil:
+			operand, up = n.SubExpression, n.Operator == "++"
+			expr = exprKey(operand) + n.Operator
+		case n.NodeType == "Assignment" && (n.Operator == "+=" || n.Operator == "-=") && n.LeftHandSide != nil && isOneLiteral(n.RightHandSide):
+			operand, up = n.LeftHandSide, n.Operator == "+="
+			expr = exprKey(operand) + " " + n.Operator + " 1"
+		case n.NodeType == "BinaryOperation" && (n.Operator == "+" || n.Operator == "-") && n.LeftExpression != nil && isOneLiteral(n.RightExpression):
+			operand, up, writes = n.LeftExpression, n.Operator == "+", false
+			expr = exprKey(operand) + " " + n.Operator + " 1"
+		default:
+			return true
+		}
+		if operand.NodeType != "Identifier" || used[operand.ReferencedDecl] {
+			return true
+		}
+		b, ok := bounds[operand.ReferencedDecl]
+		if !ok || (up && !b.up) || (!up && !b.down) {
+			return true
+		}
+		if writes {
+			used[operand.ReferencedDecl] = true
+		}
+		kind := "overflow"
+		if !up {
+			kind = "underflow"
+		}
+		g.addReport(Report{
+			RuleID:     RuleUncheckedArithmetic,
+			Issue:      fmt.Sprintf("'%s' cannot %s given %s, but still pays for the %s check", expr, kind, b.why, kind),
+			Suggestion: "Wrap it in unchecked { ... } to skip the check",
+			GasSavings: GasCheckedIncrement,
+			Location:   n.Src,
+		})
+		return true
+	})
+}
+
+// blockTimeAssumptions are literals that, combined with block.number, read
+// as assumed block times or blocks per period
+var blockTimeAssumptions = map[int]string{
+	12: "12 seconds per block", 13: "13 seconds per block", 14: "14 seconds per block", 15: "15 seconds per block",
+	240: "240 blocks per hour", 300: "300 blocks per hour",
+	5760: "5760 blocks per day", 6400: "6400 blocks per day", 6500: "6500 blocks per day", 7200: "7200 blocks per day",
+	40320: "40320 blocks per week", 45000: "45000 blocks per week", 50400: "50400 blocks per week",
+	2102400: "2102400 blocks per year", 2300000: "2300000 blocks per year", 2628000: "2628000 blocks per year",
+}
+
+// checkBlockNumberTiming detects arithmetic combining block.number with a
+// literal that assumes a block time, such as block.number * 12 or
+// block.number + 7200, which drifts whenever the block time changes and
+// does not carry over to chains with other block times
+func (g *GasOptimizer) checkBlockNumberTiming(ast *SolcASTNode) {
+	g.inspectSolcAST(ast, func(node *SolcASTNode) bool {
+		if node.NodeType != "BinaryOperation" || node.LeftExpression == nil || node.RightExpression == nil {
+			return true
+		}
+		switch node.Operator {
+		case "+", "-", "*", "/":
+		default:
+			return true
+		}
+		for _, pair := range [...][2]*SolcASTNode{{node.LeftExpression, node.RightExpression}, {node.RightExpression, node.LeftExpression}} {
+			n, ok := numberLiteral(unparen(pair[1]))
+			assumption, known := blockTimeAssumptions[n]
+			if !ok || !known || !g.usesBlockNumber(pair[0]) {
+				continue
+			}
+			g.addReport(Report{
+				RuleID:     RuleBlockNumberTiming,
+				Issue:      fmt.Sprintf("'%s' measures time in blocks, assuming %s", normalizeExpr(node), assumption),
+				Suggestion: "Use block.timestamp with time units such as 1 days, which stay correct when block times change or the contract is deployed elsewhere",
+				GasSavings: 0,
+				Location:   node.Src,
+			})
+			return false
+		}
+		return true
+	})
+}
+
+// usesBlockNumber reports whether block.number appears under node
+func (g *GasOptimizer) usesBlockNumber(node *SolcASTNode) bool {
+	found := false
+	g.inspectSolcAST(node, func(n *SolcASTNode) bool {
+		if n.NodeType == "MemberAccess" && n.MemberName == "number" && n.Expression != nil && n.Expression.Name == "block" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// checkStorageArrayNew detects a storage array assigned a new memory array,
+// such as arr = new uint256[](0), which builds the array in memory and
+// copies it over the stored one
+func (g *GasOptimizer) checkStorageArrayNew(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "Assignment" || node.Operator != "=" || node.LeftHandSide == nil || node.RightHandSide == nil {
+			return
+		}
+		call := unparen(node.RightHandSide)
+		if call.NodeType != "FunctionCall" || call.Expression == nil || call.Expression.NodeType != "NewExpression" ||
+			len(call.Arguments) != 1 || !isStorageArray(node.LeftHandSide) {
+			return
+		}
+		name := exprKey(node.LeftHandSide)
+		if name == "" {
+			name = "the array"
+		}
+		issue := fmt.Sprintf("'%s' is cleared by assigning an empty memory array, which is allocated and then copied to storage", name)
+		suggestion := fmt.Sprintf("Use delete %s, which clears the elements and length without the memory round trip", name)
+		if !isZeroLiteral(unparen(&call.Arguments[0])) {
+			issue = fmt.Sprintf("'%s' is reset by assigning a new memory array, which is allocated, zeroed and copied to storage, writing every element", name)
+			suggestion = fmt.Sprintf("Use delete %s and push elements as needed, or overwrite the elements in place if the length stays the same", name)
+		}
+		g.addReport(Report{
+			RuleID:     RuleStorageArrayNew,
+			Issue:      issue,
+			Suggestion: suggestion,
+			GasSavings: GasMemoryAlloc,
+			Location:   node.Src,
+		})
+	})
+}
+
+// checkEmptyLoops detects loops whose body does nothing observable: no
+// writes, calls, events or control flow out of the loop. Such a loop only
+// burns gas, and usually its body was lost or left unfinished by mistake.
+// For loops stepping a counter declared outside the loop are skipped, since
+// for (; i < n && a[i] != x; i++) {} is a search.
+func (g *GasOptimizer) checkEmptyLoops(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		switch loop.NodeType {
+		case "ForStatement":
+			if loop.InitializationExpression == nil || loop.InitializationExpression.NodeType != "VariableDeclarationStatement" {
+				return
+			}
+		case "WhileStatement", "DoWhileStatement":
+		default:
+			return
+		}
+		if (loop.Condition != nil && g.hasSideEffects(loop.Condition)) || (loop.Body != nil && g.hasSideEffects(loop.Body)) {
+			return // the condition or body does some work
+		}
+		what := "has an empty body"
+		if loop.Body != nil && len(loop.Body.Statements) > 0 {
+			what = "has no side effects"
+		}
+		savings, note := g.loopSavings(loop, fixedSavings(GasLoopIteration))
+		g.addReport(Report{
+			RuleID:        RuleEmptyLoop,
+			Issue:         fmt.Sprintf("Loop %s, so it only spends gas%s", what, note),
+			Suggestion:    "Check whether the body is missing a statement, such as an assignment of what it computes; otherwise remove the loop",
+			GasSavings:    savings.mid(),
+			GasSavingsMin: savings.Min,
+			GasSavingsMax: savings.Max,
+			Location:      loop.Src,
+		})
+	})
+}
+
+// hasSideEffects reports whether anything under node may write state or
+// memory, call out, log, or leave the loop or function
+func (g *GasOptimizer) hasSideEffects(node *SolcASTNode) bool {
+	found := false
+	g.inspectSolcAST(node, func(n *SolcASTNode) bool {
+		switch n.NodeType {
+		case "Assignment", "FunctionCall", "EmitStatement", "InlineAssembly", "Return", "Break",
+			"RevertStatement", "Throw", "TryStatement":
+			found = true
+		case "UnaryOperation":
+			found = n.Operator == "++" || n.Operator == "--" || n.Operator == "delete"
+		}
+		return !found
+	})
+	return found
+}
+
+// checkLoopMappingWrites detects a storage mapping entry written on every
+// iteration of a loop under the same key, as in balances[user] += x with
+// user fixed across iterations. Every write after the first is an SSTORE to
+// a slot already dirty, plus an SLOAD for compound assignments, where a
+// local accumulator would write it once after the loop.
+func (g *GasOptimizer) checkLoopMappingWrites(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		if (loop.NodeType != "ForStatement" && loop.NodeType != "WhileStatement" && loop.NodeType != "DoWhileStatement") || loop.Body == nil {
+			return
+		}
+		declared := g.declaredDecls(loop)
+		varying := g.writtenDecls(loop)
+		for id := range declared {
+			varying[id] = true
+		}
+		type write struct {
+			node         *SolcASTNode
+			count, reads int
+		}
+		writes := make(map[string]*write)
+		var order []string
+		g.inspectSolcAST(loop.Body, func(node *SolcASTNode) bool {
+			if node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" || node.NodeType == "DoWhileStatement" {
+				return false // reported against the innermost loop
+			}
+			var target *SolcASTNode
+			compound := false
+			switch {
+			case node.NodeType == "Assignment":
+				target, compound = node.LeftHandSide, node.Operator != "="
+			case node.NodeType == "UnaryOperation" && (node.Operator == "++" || node.Operator == "--"):
+				target, compound = node.SubExpression, true
+			}
+			if target == nil || !g.invariantMappingEntry(target, varying, declared) {
+				return true
+			}
+			key := exprKey(target)
+			if key == "" {
+				return true
+			}
+			w, ok := writes[key]
+			if !ok {
+				w = &write{node: node}
+				writes[key] = w
+				order = append(order, key)
+			}
+			w.count++
+			if compound {
+				w.reads++
+			}
+			return true
+		})
+		for _, key := range order {
+			w := writes[key]
+			savings, note := g.loopSavings(loop, fixedSavings((w.count+w.reads)*g.gas.SloadWarm))
+			g.addReport(Report{
+				RuleID:        RuleLoopMappingWrite,
+				Issue:         fmt.Sprintf("%s is written on every loop iteration with the same key, paying a warm SSTORE each time%s", key, note),
+				Suggestion:    fmt.Sprintf("Accumulate the value in a local variable inside the loop and assign it to %s once after the loop", key),
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      w.node.Src,
+			})
+		}
+	})
+}
+
+// invariantMappingEntry reports whether node is an entry of a storage
+// mapping, such as m[k] or m[a][b], whose keys reference no varying
+// declaration and whose mapping is not declared in the loop. Keys may only
+// be identifiers, literals and member accesses such as msg.sender; calls and
+// nested indexing are taken to vary.
+func (g *GasOptimizer) invariantMappingEntry(node *SolcASTNode, varying, declared map[int]bool) bool {
+	if node.NodeType != "IndexAccess" {
+		return false
+	}
+	for node.NodeType == "IndexAccess" {
+		base := node.BaseExpression
+		if base == nil || node.IndexExpression == nil || base.TypeDescriptions == nil ||
+			!strings.HasPrefix(base.TypeDescriptions.TypeIdentifier, "t_mapping") {
+			return false
+		}
+		invariant := true
+		g.inspectSolcAST(node.IndexExpression, func(n *SolcASTNode) bool {
+			switch n.NodeType {
+			case "Identifier":
+				invariant = invariant && !varying[n.ReferencedDecl]
+			case "Literal", "MemberAccess":
+			default:
+				invariant = false
+			}
+			return invariant
+		})
+		if !invariant {
+			return false
+		}
+		node = base
+	}
+	id := baseDecl(node)
+	return id != 0 && !declared[id]
+}
+
+// checkBareReverts detects revert() and require(false) without a reason in
+// public and external functions. Callers only see an empty revert; a
+// custom error costs about the same and says what went wrong. Custom
+// errors need Solidity 0.8.4, so older pragmas are skipped.
+func (g *GasOptimizer) checkBareReverts(ast *SolcASTNode) {
+	if min, ok := g.pragmaMinVersion(ast); ok && !min.atLeast(solcVersion{0, 8, 4}) {
+		return
+	}
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil || (fn.Visibility != "public" && fn.Visibility != "external") {
+			return
+		}
+		g.walkSolcAST(fn.Body, func(call *SolcASTNode) {
+			if call.NodeType != "FunctionCall" || call.Expression == nil || call.Expression.NodeType != "Identifier" {
+				return
+			}
+			var what string
+			switch {
+			case call.Expression.Name == "revert" && len(call.Arguments) == 0:
+				what = "revert()"
+			case call.Expression.Name == "require" && len(call.Arguments) == 1 &&
+				isBoolLiteral(&call.Arguments[0]) && call.Arguments[0].Value == "false":
+				what = "require(false)"
+			default:
+				return
+			}
+			g.addReport(Report{
+				RuleID:     RuleBareRevert,
+				Issue:      fmt.Sprintf("%s in %s function '%s' reverts without a reason, so callers cannot tell why", what, fn.Visibility, fn.Name),
+				Suggestion: "Revert with a custom error, e.g. revert Unauthorized(); it costs about the same and identifies the failure",
+				GasSavings: 0,
+				Location:   call.Src,
+			})
+		})
+	})
+}
+
+// checkVariableLoopSteps detects for loops stepping their counter by a
+// state variable the loop does not write, as in i += step: every iteration
+// pays a warm SLOAD for the same value. A step that is initialized with a
+// literal and never written can be constant instead.
+func (g *GasOptimizer) checkVariableLoopSteps(ast *SolcASTNode) {
+	state := g.stateVariables(ast)
+	if len(state) == 0 {
+		return
+	}
+	literal := make(map[int]bool) // state variables of this source initialized with a number
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "ContractDefinition" {
+			return
+		}
+		for i := range node.Nodes {
+			if member := &node.Nodes[i]; state[member.ID] && member.InitialValue != nil {
+				_, literal[member.ID] = numberLiteral(member.InitialValue)
+			}
+		}
+	})
+	written := g.storageWrites(ast)
+	g.walkSolcAST(ast, func(loop *SolcASTNode) {
+		if loop.NodeType != "ForStatement" || loop.LoopExpression == nil {
+			return
+		}
+		update := loop.LoopExpression
+		if update.NodeType == "ExpressionStatement" && update.Expression != nil {
+			update = update.Expression
+		}
+		if update.NodeType != "Assignment" || update.LeftHandSide == nil || update.RightHandSide == nil ||
+			update.LeftHandSide.NodeType != "Identifier" {
+			return
+		}
+		inLoop := g.storageWrites(loop)
+		seen := make(map[int]bool)
+		g.walkSolcAST(update.RightHandSide, func(step *SolcASTNode) {
+			id := step.ReferencedDecl
+			if step.NodeType != "Identifier" || !state[id] || inLoop[id] || seen[id] {
+				return
+			}
+			seen[id] = true
+			savings, note := g.loopSavings(loop, fixedSavings(g.gas.SloadWarm))
+			suggestion := fmt.Sprintf("Copy '%s' to a local before the loop and step by the local", step.Name)
+			if literal[id] && !written[id] {
+				suggestion = fmt.Sprintf("'%s' is never written after its initializer: declare it constant", step.Name)
+			}
+			g.addReport(Report{
+				RuleID:        RuleVariableLoopStep,
+				Issue:         fmt.Sprintf("Loop counter '%s' is stepped by state variable '%s', which is read from storage on every iteration%s", update.LeftHandSide.Name, step.Name, note),
+				Suggestion:    suggestion,
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      update.Src,
+			})
+		})
+	})
+}
+
+// checkExpensiveViews detects public and external view functions with
+// loops that read storage. Calls from off-chain are free, but a contract
+// calling the function on-chain pays for every SLOAD of every iteration.
+// The cost is estimated from the state reads of the outermost loops, so
+// nested loops are undercounted.
+func (g *GasOptimizer) checkExpensiveViews(ast *SolcASTNode) {
+	state := g.stateVariables(ast)
+	if len(state) == 0 {
+		return
+	}
+	g.walkSolcAST(ast, func(fn *SolcASTNode) {
+		if fn.NodeType != "FunctionDefinition" || fn.Body == nil || fn.StateMutability != "view" ||
+			(fn.Visibility != "public" && fn.Visibility != "external") {
+			return
+		}
+		loops := 0
+		var cost savingsRange
+		var note string
+		g.inspectSolcAST(fn.Body, func(loop *SolcASTNode) bool {
+			if loop.NodeType != "ForStatement" && loop.NodeType != "WhileStatement" && loop.NodeType != "DoWhileStatement" {
+				return true
+			}
+			reads := 0
+			g.walkSolcAST(loop, func(n *SolcASTNode) {
+				if n.NodeType == "Identifier" && state[n.ReferencedDecl] {
+					reads++
+				}
+			})
+			if reads > 0 {
+				c, n := g.loopSavings(loop, savingsRange{reads * g.gas.SloadWarm, reads * g.gas.SloadCold})
+				if loops > 0 && n != note {
+					n = "" // loops with different trip counts
+				}
+				cost.Min += c.Min
+				cost.Max += c.Max
+				note = n
+				loops++
+			}
+			return false // nested loops count towards the outermost one
+		})
+		if loops == 0 {
+			return
+		}
+		g.addReport(Report{
+			RuleID:     RuleExpensiveView,
+			Issue:      fmt.Sprintf("view function '%s' reads storage in %d loop(s), ~%d gas per call%s; free off-chain, but paid by contracts calling it on-chain", fn.Name, loops, cost.mid(), note),
+			Suggestion: "Document the cost for integrators, or add a paginated variant (offset, limit) for on-chain callers",
+			GasSavings: 0,
+			Location:   fn.Src,
+		})
+	})
+}
+
+// checkBranchStorageReads detects value-type state variables read in both
+// branches of an if, neither branch writing them first. An else-if chain
+// is one statement: an inner if counts the variables its condition reads
+// or all of its branches do. Hoisting the read above the if only saves
+// runtime gas when the condition reads the variable too; otherwise it
+// saves the duplicated SLOAD code.
+func (g *GasOptimizer) checkBranchStorageReads(ast *SolcASTNode) {
+	state := g.stateVariables(ast)
+	if len(state) == 0 {
+		return
+	}
+	covered := make(map[*SolcASTNode]map[int]bool) // else-ifs and the variables reported for their chain
+	g.walkSolcAST(ast, func(stmt *SolcASTNode) {
+		if stmt.NodeType != "IfStatement" || stmt.Condition == nil || stmt.TrueBody == nil || stmt.FalseBody == nil {
+			return
+		}
+		inCondition := g.branchReads(stmt.Condition, state)
+		written := g.writtenDecls(stmt.Condition)
+		inFalse := g.branchReads(stmt.FalseBody, state)
+		var both []*SolcASTNode
+		for id, read := range g.branchReads(stmt.TrueBody, state) {
+			if inFalse[id] != nil && !written[id] && !covered[stmt][id] && !g.Config.isExempt(read.Name) {
+				both = append(both, read)
+			}
+		}
+		slices.SortFunc(both, func(a, b *SolcASTNode) int { return srcStart(a) - srcStart(b) })
+		reported := make(map[int]bool)
+		for id := range covered[stmt] {
+			reported[id] = true
+		}
+		for _, read := range both {
+			reported[read.ReferencedDecl] = true
+			issue := fmt.Sprintf("State variable '%s' is read in both branches of an if; one read above it would shrink the code", read.Name)
+			savings := 0
+			if inCondition[read.ReferencedDecl] != nil {
+				issue = fmt.Sprintf("State variable '%s' is read by an if condition and again in both branches", read.Name)
+				savings = g.gas.SloadWarm - g.gas.Mload
+			}
+			g.addReport(Report{
+				RuleID:     RuleBranchStorageRead,
+				Issue:      issue,
+				Suggestion: fmt.Sprintf("Read '%s' into a local before the if and use the local in both branches", read.Name),
+				GasSavings: savings,
+				Location:   stmt.Src,
+			})
+		}
+		if next := stmt.FalseBody; next.NodeType == "IfStatement" {
+			covered[next] = reported
+		}
+	})
+}
+
+// branchReads maps each value-type state variable read under node before
+// node writes it to its first read. For an if statement only the variables
+// read on every path count.
+func (g *GasOptimizer) branchReads(node *SolcASTNode, state map[int]bool) map[int]*SolcASTNode {
+	if node.NodeType == "IfStatement" {
+		reads := make(map[int]*SolcASTNode)
+		if node.Condition != nil {
+			reads = g.branchReads(node.Condition, state)
+		}
+		if node.TrueBody != nil && node.FalseBody != nil {
+			inFalse := g.branchReads(node.FalseBody, state)
+			for id, read := range g.branchReads(node.TrueBody, state) {
+				if inFalse[id] != nil && reads[id] == nil {
+					reads[id] = read
+				}
+			}
+		}
+		return reads
+	}
+	// An assignment writes when it completes, so x = x + 1 reads x first
+	targets := make(map[*SolcASTNode]bool) // identifiers only assigned, as in x = 1
+	firstWrite := make(map[int]int)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		var lhs *SolcASTNode
+		switch {
+		case n.NodeType == "Assignment" && n.LeftHandSide != nil:
+			lhs = n.LeftHandSide
+			targets[lhs] = n.Operator == "="
+		case n.NodeType == "UnaryOperation" && (n.Operator == "++" || n.Operator == "--" || n.Operator == "delete") && n.SubExpression != nil:
+			lhs = n.SubExpression
+		default:
+			return
+		}
+		start, length, _ := parseSrc(n.Src)
+		if end, ok := firstWrite[baseDecl(lhs)]; !ok || start+length < end {
+			firstWrite[baseDecl(lhs)] = start + length
+		}
+	})
+	reads := make(map[int]*SolcASTNode)
+	g.walkSolcAST(node, func(n *SolcASTNode) {
+		id := n.ReferencedDecl
+		if n.NodeType != "Identifier" || !state[id] || targets[n] || dataLocation(n) != "" {
+			return
+		}
+		if end, ok := firstWrite[id]; ok && srcStart(n) >= end {
+			return
+		}
+		if first := reads[id]; first == nil || srcStart(n) < srcStart(first) {
+			reads[id] = n
+		}
+	})
+	return reads
+}
+
+// srcStart returns the source offset where node starts
+func srcStart(node *SolcASTNode) int {
+	start, _, _ := parseSrc(node.Src)
+	return start
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// forLoopJSON is function f() { for (uint i = 0; i < 10; i++) <body> }
+// where body is the JSON of the loop body
+func forLoopJSON(body string) string {
+	return `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:200:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","src":"10:180:0","body":{"nodeType":"Block","src":"20:160:0","statements":[
+{"nodeType":"ForStatement","src":"30:140:0",
+ "initializationExpression":{"nodeType":"VariableDeclarationStatement","src":"35:10:0","declarations":[{"nodeType":"VariableDeclaration","name":"i","id":3,"src":"35:6:0"}],
+  "initialValue":{"nodeType":"Literal","kind":"number","value":"0","src":"44:1:0"}},
+ "condition":{"nodeType":"BinaryOperation","operator":"<","src":"47:6:0",
+  "leftExpression":{"nodeType":"Identifier","name":"i","referencedDeclaration":3,"src":"47:1:0"},
+  "rightExpression":{"nodeType":"Literal","kind":"number","value":"10","src":"51:2:0"}},
+ "loopExpression":{"nodeType":"ExpressionStatement","src":"55:3:0","expression":{"nodeType":"UnaryOperation","operator":"++","src":"55:3:0",
+  "subExpression":{"nodeType":"Identifier","name":"i","referencedDeclaration":3,"src":"55:1:0"}}},
+ "body":` + body + `}]}}]}]}`
+}
+
+func TestEmptyLoop(t *testing.T) {
+	tests := []struct {
+		name, body, issue string
+	}{
+		{"stray semicolon", `{"nodeType":"EmptyStatement","src":"60:1:0"}`, "Loop has an empty body"},
+		{"empty block", `{"nodeType":"Block","src":"60:2:0","statements":[]}`, "Loop has an empty body"},
+		{"unused local", `{"nodeType":"Block","src":"60:30:0","statements":[
+			{"nodeType":"VariableDeclarationStatement","src":"62:16:0","declarations":[{"nodeType":"VariableDeclaration","name":"x","id":4,"src":"62:6:0"}],
+			 "initialValue":{"nodeType":"BinaryOperation","operator":"*","src":"71:5:0",
+			  "leftExpression":{"nodeType":"Identifier","name":"i","referencedDeclaration":3,"src":"71:1:0"},
+			  "rightExpression":{"nodeType":"Literal","kind":"number","value":"2","src":"75:1:0"}}}]}`, "Loop has no side effects"},
+		{"assignment", `{"nodeType":"Block","src":"60:30:0","statements":[
+			{"nodeType":"ExpressionStatement","src":"62:12:0","expression":{"nodeType":"Assignment","operator":"+=","src":"62:12:0",
+			 "leftHandSide":{"nodeType":"Identifier","name":"total","referencedDeclaration":1,"src":"62:5:0"},
+			 "rightHandSide":{"nodeType":"Identifier","name":"i","referencedDeclaration":3,"src":"71:1:0"}}}]}`, ""},
+		{"event", `{"nodeType":"Block","src":"60:30:0","statements":[
+			{"nodeType":"EmitStatement","src":"62:12:0","eventCall":{"nodeType":"FunctionCall","src":"67:6:0",
+			 "expression":{"nodeType":"Identifier","name":"Seen","src":"67:4:0"},
+			 "arguments":[{"nodeType":"Identifier","name":"i","referencedDeclaration":3,"src":"72:1:0"}]}}]}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := analyzeJSON(t, forLoopJSON(tt.body), RuleEmptyLoop)
+			switch {
+			case tt.issue == "" && len(reports) != 0:
+				t.Errorf("reports = %+v, want none", reports)
+			case tt.issue != "" && (len(reports) != 1 || !strings.HasPrefix(reports[0].Issue, tt.issue)):
+				t.Errorf("reports = %+v, want one starting %q", reports, tt.issue)
+			}
+		})
+	}
+}
+
+// safeMathJSON is x.sol under pragma, calling a.<member>(b) resolved to
+// function 11 of library C.sol's Lib, next to OpenZeppelin's
+// SignedSafeMath.sol, whose function 21 is sub
+func safeMathJSON(pragma, lib, member string, ref int) string {
+	pragmaLiterals, _ := json.Marshal(append([]string{"solidity"}, strings.Fields(pragma)...))
+	return `[{"nodeType":"SourceUnit","absolutePath":"x.sol","src":"0:200:0","nodes":[
+ {"nodeType":"PragmaDirective","src":"0:23:0","literals":` + string(pragmaLiterals) + `},
+ {"nodeType":"ContractDefinition","name":"` + lib + `","contractKind":"library","src":"30:40:0","nodes":[
+  {"nodeType":"FunctionDefinition","name":"add","id":11,"src":"40:20:0"}]},
+ {"nodeType":"ContractDefinition","name":"C","contractKind":"contract","src":"80:100:0","nodes":[
+  {"nodeType":"FunctionDefinition","name":"f","id":12,"src":"90:80:0","body":{"nodeType":"Block","src":"100:60:0","statements":[
+   {"nodeType":"Return","src":"110:20:0","expression":{"nodeType":"FunctionCall","src":"117:8:0",
+    "expression":{"nodeType":"MemberAccess","memberName":"` + member + `","referencedDeclaration":` + strconv.Itoa(ref) + `,"src":"117:5:0",
+     "expression":{"nodeType":"Identifier","name":"a","src":"117:1:0","typeDescriptions":{"typeIdentifier":"t_uint256","typeString":"uint256"}}},
+    "arguments":[{"nodeType":"Identifier","name":"b","src":"123:1:0"}]}}]}}]}]},
+{"nodeType":"SourceUnit","absolutePath":"@openzeppelin/contracts/utils/math/SignedSafeMath.sol","src":"0:100:1","nodes":[
+ {"nodeType":"ContractDefinition","name":"SignedSafeMath","contractKind":"library","src":"0:100:1","nodes":[
+  {"nodeType":"FunctionDefinition","name":"sub","id":21,"src":"10:20:1"}]}]}]`
+}
+
+func TestSafeMathOnChecked(t *testing.T) {
+	tests := []struct {
+		name, pragma, lib, member string
+		ref                       int
+		want                      bool
+	}{
+		{"SafeMath library", "^ 0.8 .0", "SafeMath", "add", 11, true},
+		{"OpenZeppelin import", "^ 0.8 .0", "SafeMath", "sub", 21, true},
+		{"saturating library", "^ 0.8 .0", "SaturatingMath", "add", 11, false},
+		{"unresolved call", "^ 0.8 .0", "SafeMath", "add", 0, false},
+		{"0.7 allowed", "^ 0.7 .0 || ^ 0.8 .0", "SafeMath", "add", 11, false},
+		{"0.7 only", "^ 0.7 .6", "SafeMath", "add", 11, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := analyzeJSON(t, safeMathJSON(tt.pragma, tt.lib, tt.member, tt.ref), RuleSafeMathOnChecked)
+			if got := len(reports) == 1; got != tt.want || len(reports) > 1 {
+				t.Errorf("reports = %+v, want reported = %v", reports, tt.want)
+			}
+		})
+	}
+}
+
+// requireStringJSON is require(ok, message) under pragma, or without one
+// when pragma is empty
+func requireStringJSON(pragma, message string) string {
+	directive := ""
+	if pragma != "" {
+		literals, _ := json.Marshal(append([]string{"solidity"}, strings.Fields(pragma)...))
+		directive = `{"nodeType":"PragmaDirective","src":"0:23:0","literals":` + string(literals) + `},`
+	}
+	quoted, _ := json.Marshal(message)
+	return `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[` + directive + `
+ {"nodeType":"ExpressionStatement","src":"30:60:0","expression":{"nodeType":"FunctionCall","src":"30:59:0",
+  "expression":{"nodeType":"Identifier","name":"require","src":"30:7:0"},
+  "arguments":[{"nodeType":"Identifier","name":"ok","src":"38:2:0"},
+   {"nodeType":"Literal","kind":"string","value":` + string(quoted) + `,"src":"42:40:0"}]}}]}`
+}
+
+func TestRequireStringSuggestion(t *testing.T) {
+	short, long := "not owner", strings.Repeat("x", 40)
+	tests := []struct {
+		name, pragma, message string
+		want                  string // suggestion prefix, empty for no report
+		savings               int
+	}{
+		{"0.8.26", "^ 0.8 .26", short, "Replace the string with a custom error: require(cond, CustomError())", GasRevertStringWord},
+		{"0.8.4", "^ 0.8 .4", short, "Replace the string with a custom error: if (!cond) revert CustomError();", GasRevertStringWord},
+		{"0.8.4 or 0.8.26", ">= 0.8 .26 || ^ 0.8 .4", long, "Replace the string with a custom error: if (!cond) revert CustomError();", 2 * GasRevertStringWord},
+		{"no pragma", "", short, "Replace the string with a custom error: if (!cond) revert CustomError();", GasRevertStringWord},
+		{"0.8.0 short", "^ 0.8 .0", short, "", 0},
+		{"0.7 long", "^ 0.7 .6", long, "Shorten the message", GasRevertStringWord},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports := analyzeJSON(t, requireStringJSON(tt.pragma, tt.message), RuleRequireString)
+			if tt.want == "" {
+				if len(reports) != 0 {
+					t.Errorf("reports = %+v, want none", reports)
+				}
+				return
+			}
+			if len(reports) != 1 {
+				t.Fatalf("reports = %+v, want one", reports)
+			}
+			if !strings.HasPrefix(reports[0].Suggestion, tt.want) {
+				t.Errorf("suggestion = %q, want %q", reports[0].Suggestion, tt.want)
+			}
+			if reports[0].GasSavings != tt.savings {
+				t.Errorf("savings = %d, want %d", reports[0].GasSavings, tt.savings)
+			}
+		})
+	}
+}
+
+// unusedParamJSON is function f(uint x) external { <statement> }
+func unusedParamJSON(statement string) string {
+	return `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:200:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","visibility":"external","src":"10:180:0",
+ "parameters":{"parameters":[{"nodeType":"VariableDeclaration","name":"x","id":5,"src":"21:6:0","typeDescriptions":{"typeString":"uint256"}}]},
+ "body":{"nodeType":"Block","src":"40:140:0","statements":[` + statement + `]}}]}]}`
+}
+
+// sstoreXJSON is assembly { sstore(0, x) }, with x resolved to declaration
+// 5 when refs is set
+func sstoreXJSON(refs bool) string {
+	external := ""
+	if refs {
+		external = `"externalReferences":[{"declaration":5,"src":"70:1:0","valueSize":1}],`
+	}
+	return `{"nodeType":"InlineAssembly","src":"50:30:0",` + external + `"AST":{"nodeType":"YulBlock","src":"59:20:0","statements":[
+ {"nodeType":"YulExpressionStatement","src":"60:12:0","expression":{"nodeType":"YulFunctionCall","src":"60:12:0",
+  "functionName":{"nodeType":"YulIdentifier","name":"sstore","src":"60:6:0"},
+  "arguments":[{"nodeType":"YulLiteral","kind":"number","value":"0","src":"67:1:0"},{"nodeType":"YulIdentifier","name":"x","src":"70:1:0"}]}}]}}`
+}
+
+func TestUnusedParameter(t *testing.T) {
+	tests := []struct {
+		name, statement string
+		want            int
+	}{
+		{"unused", `{"nodeType":"Return","src":"50:7:0"}`, 1},
+		{"emitted", `{"nodeType":"EmitStatement","src":"50:10:0","eventCall":{"nodeType":"FunctionCall","src":"55:4:0",
+			"expression":{"nodeType":"Identifier","name":"E","referencedDeclaration":2,"src":"55:1:0"},
+			"arguments":[{"nodeType":"Identifier","name":"x","referencedDeclaration":5,"src":"57:1:0"}]}}`, 0},
+		{"assembly", sstoreXJSON(true), 0},
+		{"assembly without references", sstoreXJSON(false), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reports := analyzeJSON(t, unusedParamJSON(tt.statement), RuleUnusedParameter); len(reports) != tt.want {
+				t.Errorf("reports = %+v, want %d", reports, tt.want)
+			}
+		})
+	}
+}
+
+// storagePointerJSON is P storage p = positions[1]; <statement>
+func storagePointerJSON(statement string) string {
+	return `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:200:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","src":"10:180:0","body":{"nodeType":"Block","src":"20:160:0","statements":[
+ {"nodeType":"VariableDeclarationStatement","src":"22:28:0",
+  "declarations":[{"nodeType":"VariableDeclaration","name":"p","id":7,"storageLocation":"storage","src":"22:11:0"}],
+  "initialValue":{"nodeType":"IndexAccess","src":"36:12:0",
+   "baseExpression":{"nodeType":"Identifier","name":"positions","referencedDeclaration":1,"src":"36:9:0"},
+   "indexExpression":{"nodeType":"Literal","kind":"number","value":"1","src":"46:1:0"}}},
+ ` + statement + `]}}]}]}`
+}
+
+// sloadPJSON is assembly { pop(sload(p.slot)) }, with p.slot resolved to
+// declaration 7 when refs is set
+func sloadPJSON(refs bool) string {
+	external := ""
+	if refs {
+		external = `"externalReferences":[{"declaration":7,"src":"75:6:0","suffix":"slot","valueSize":1}],`
+	}
+	return `{"nodeType":"InlineAssembly","src":"55:30:0",` + external + `"AST":{"nodeType":"YulBlock","src":"64:20:0","statements":[
+ {"nodeType":"YulExpressionStatement","src":"65:17:0","expression":{"nodeType":"YulFunctionCall","src":"65:17:0",
+  "functionName":{"nodeType":"YulIdentifier","name":"pop","src":"65:3:0"},
+  "arguments":[{"nodeType":"YulFunctionCall","src":"69:12:0","functionName":{"nodeType":"YulIdentifier","name":"sload","src":"69:5:0"},
+   "arguments":[{"nodeType":"YulIdentifier","name":"p.slot","src":"75:6:0"}]}]}}]}}`
+}
+
+func TestStoragePointerUses(t *testing.T) {
+	tests := []struct {
+		name, statement string
+		want            int
+	}{
+		{"unused", `{"nodeType":"Return","src":"55:7:0"}`, 1},
+		{"emitted", `{"nodeType":"EmitStatement","src":"55:20:0","eventCall":{"nodeType":"FunctionCall","src":"60:14:0",
+			"expression":{"nodeType":"Identifier","name":"E","referencedDeclaration":2,"src":"60:1:0"},
+			"arguments":[{"nodeType":"MemberAccess","memberName":"amount","src":"62:8:0",
+			 "expression":{"nodeType":"Identifier","name":"p","referencedDeclaration":7,"src":"62:1:0"}}]}}`, 0},
+		{"assembly", sloadPJSON(true), 0},
+		{"assembly without references", sloadPJSON(false), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reports := analyzeJSON(t, storagePointerJSON(tt.statement), RuleStoragePointer); len(reports) != tt.want {
+				t.Errorf("reports = %+v, want %d", reports, tt.want)
+			}
+		})
+	}
+}
+
+// constructorParamJSON is contract C { address owner; constructor(address o) { owner = o; } }
+const constructorParamJSON = `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[{"nodeType":"ContractDefinition","name":"C","id":1,"src":"0:200:0","nodes":[
+ {"nodeType":"VariableDeclaration","name":"owner","id":2,"stateVariable":true,"src":"14:13:0","typeDescriptions":{"typeString":"address"}},
+ {"nodeType":"FunctionDefinition","name":"","kind":"constructor","id":3,"src":"30:60:0",
+  "parameters":{"parameters":[{"nodeType":"VariableDeclaration","name":"o","id":4,"src":"42:9:0","typeDescriptions":{"typeString":"address"}}]},
+  "body":{"nodeType":"Block","src":"53:30:0","statements":[
+   {"nodeType":"ExpressionStatement","src":"55:10:0","expression":{"nodeType":"Assignment","operator":"=","src":"55:9:0",
+    "leftHandSide":{"nodeType":"Identifier","name":"owner","referencedDeclaration":2,"src":"55:5:0"},
+    "rightHandSide":{"nodeType":"Identifier","name":"o","referencedDeclaration":4,"src":"63:1:0"}}}]}}]}]}`
+
+func TestConstructorOnlyLeavesParamsToGAS050(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          *Config
+		disable         string
+		include         string
+		param, ctorOnly int
+	}{
+		{"both on", nil, "", "", 1, 0},
+		{"GAS050 disabled", nil, RuleConstructorParam, "", 0, 1},
+		{"GAS050 off in config", &Config{Rules: map[string]Level{RuleConstructorParam: LevelOff}}, "", "", 0, 1},
+		{"GAS050 not included", nil, "", RuleConstructorOnly, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.config.ApplyRuleFlags("", tt.disable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.include != "" {
+				if cfg, err = cfg.ApplyIncludeOnly(tt.include); err != nil {
+					t.Fatal(err)
+				}
+			}
+			root, err := decodeSolcAST("x.sol", []byte(constructorParamJSON))
+			if err != nil {
+				t.Fatal(err)
+			}
+			g := &GasOptimizer{Path: "x.sol", AST: root, Config: cfg, Reports: []Report{}}
+			g.Analyze()
+			if n := len(reportsOf(g.Reports, RuleConstructorParam)); n != tt.param {
+				t.Errorf("%d %s reports, want %d", n, RuleConstructorParam, tt.param)
+			}
+			if n := len(reportsOf(g.Reports, RuleConstructorOnly)); n != tt.ctorOnly {
+				t.Errorf("%d %s reports, want %d", n, RuleConstructorOnly, tt.ctorOnly)
+			}
+		})
+	}
+}
+
+// allocationJSON is a loop body declaring T memory v = <init>, followed by
+// the given statements
+func allocationJSON(init string, statements ...string) string {
+	body := `{"nodeType":"Block","src":"60:100:0","statements":[
+ {"nodeType":"VariableDeclarationStatement","src":"62:30:0",
+  "declarations":[{"nodeType":"VariableDeclaration","name":"v","id":10,"storageLocation":"memory","src":"62:10:0"}],
+  "initialValue":` + init + `}`
+	for _, stmt := range statements {
+		body += ",\n " + stmt
+	}
+	return body + "]}"
+}
+
+// callJSON is a call of name with the given state mutability and arguments
+func callJSON(name, mutability string, args ...string) string {
+	return `{"nodeType":"FunctionCall","kind":"functionCall","src":"75:10:0",
+ "expression":{"nodeType":"Identifier","name":"` + name + `","referencedDeclaration":20,"src":"75:4:0",
+  "typeDescriptions":{"typeString":"function (uint256) ` + mutability + ` returns (uint256)"}},
+ "arguments":[` + strings.Join(args, ",") + `]}`
+}
+
+func TestLoopAllocation(t *testing.T) {
+	const (
+		newArray = `{"nodeType":"FunctionCall","kind":"functionCall","src":"75:20:0",
+			"expression":{"nodeType":"NewExpression","src":"75:14:0","typeDescriptions":{"typeString":"function (uint256) pure returns (uint256[] memory)"}},
+			"arguments":[{"nodeType":"Literal","kind":"number","value":"4","src":"92:1:0"}]}`
+		storageCopy = `{"nodeType":"IndexAccess","src":"75:8:0","typeDescriptions":{"typeIdentifier":"t_struct$_Item_$5_storage","typeString":"struct C.Item storage ref"},
+			"baseExpression":{"nodeType":"Identifier","name":"items","referencedDeclaration":1,"src":"75:5:0"},
+			"indexExpression":{"nodeType":"Identifier","name":"k","referencedDeclaration":2,"src":"81:1:0"}}`
+		update = `{"nodeType":"ExpressionStatement","src":"100:10:0","expression":` + `{"nodeType":"FunctionCall","kind":"functionCall","src":"100:9:0",
+			"expression":{"nodeType":"Identifier","name":"update","referencedDeclaration":21,"src":"100:6:0","typeDescriptions":{"typeString":"function (uint256)"}},
+			"arguments":[{"nodeType":"Identifier","name":"k","referencedDeclaration":2,"src":"107:1:0"}]}}`
+		k = `{"nodeType":"Identifier","name":"k","referencedDeclaration":2,"src":"80:1:0"}`
+	)
+	structLiteral := func(arg string) string {
+		return `{"nodeType":"FunctionCall","kind":"structConstructorCall","src":"75:10:0",
+			"expression":{"nodeType":"Identifier","name":"Item","referencedDeclaration":5,"src":"75:4:0"},"arguments":[` + arg + `]}`
+	}
+	tests := []struct {
+		name, body string
+		want       int
+	}{
+		{"new array", allocationJSON(newArray), 1},
+		{"array literal", allocationJSON(`{"nodeType":"TupleExpression","isInlineArray":true,"src":"75:6:0","components":[` + k + `]}`), 1},
+		{"struct literal", allocationJSON(structLiteral(k)), 1},
+		{"struct literal of a view call", allocationJSON(structLiteral(callJSON("price", "view", k))), 0},
+		{"struct literal of a pure call", allocationJSON(structLiteral(callJSON("scale", "pure", k))), 1},
+		{"storage copy", allocationJSON(storageCopy), 1},
+		{"storage copy with a state-changing call", allocationJSON(storageCopy, update), 0},
+		{"pointer copy", allocationJSON(`{"nodeType":"Identifier","name":"other","referencedDeclaration":3,"src":"75:5:0",
+			"typeDescriptions":{"typeIdentifier":"t_array$_t_uint256_$dyn_memory_ptr","typeString":"uint256[] memory"}}`), 0},
+		{"view call", allocationJSON(callJSON("getItem", "view", k)), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reports := analyzeJSON(t, forLoopJSON(tt.body), RuleLoopAllocation); len(reports) != tt.want {
+				t.Errorf("reports = %+v, want %d", reports, tt.want)
+			}
+		})
+	}
+
+	doWhile := `{"nodeType":"SourceUnit","src":"0:200:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:200:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","src":"10:180:0","body":{"nodeType":"Block","src":"20:160:0","statements":[
+{"nodeType":"DoWhileStatement","src":"30:140:0","condition":{"nodeType":"Identifier","name":"more","referencedDeclaration":4,"src":"165:4:0"},
+ "body":` + allocationJSON(newArray) + `}]}}]}]}`
+	if reports := analyzeJSON(t, doWhile, RuleLoopAllocation); len(reports) != 1 {
+		t.Errorf("do-while reports = %+v, want 1", reports)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// skipDirs are dependency and build directories not worth analyzing
+var skipDirs = map[string]bool{"node_modules": true, "lib": true, "out": true, "cache": true, "artifacts": true}
+
+// solidityFiles lists the .sol files under dir, skipping hidden and
+// dependency directories
+func solidityFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || skipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".sol") {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// AnalyzeDir analyzes every .sol file under dir with a pool of jobs
+// workers. If ctx is cancelled, in-flight solc processes are killed and the
+// optimizers finished so far are returned with ctx.Err().
+func AnalyzeDir(ctx context.Context, dir string, cfg *Config, opts Options, jobs int) ([]*GasOptimizer, error) {
+	files, err := solidityFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return AnalyzeFiles(ctx, files, cfg, opts, jobs)
+}
+
+// expandPaths replaces each directory in paths with the .sol files under
+// it, dropping files named more than once
+func expandPaths(paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		expanded := []string{path}
+		if isDir(path) {
+			var err error
+			if expanded, err = solidityFiles(path); err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range expanded {
+			if clean := filepath.Clean(file); !seen[clean] {
+				seen[clean] = true
+				files = append(files, file)
+			}
+		}
+	}
+	return files, nil
+}
+
+// AnalyzeFiles analyzes files concurrently, returning results sorted by
+// path. The first error stops further work; on cancellation the completed
+// results are still returned.
+func AnalyzeFiles(ctx context.Context, files []string, cfg *Config, opts Options, jobs int) ([]*GasOptimizer, error) {
+	if jobs < 1 {
+		jobs = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	paths := make(chan string)
+	var (
+		mu       sync.Mutex
+		results  []*GasOptimizer
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				g, err := NewGasOptimizerOptions(ctx, path, opts)
+				if err == nil {
+					g.Config = cfg
+					g.Context = opts.Context
+					g.MaxReports = opts.MaxReports
+					g.Analyze()
+				}
+				mu.Lock()
+				if err == nil {
+					results = append(results, g)
+				} else if firstErr == nil && ctx.Err() == nil {
+					firstErr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+dispatch:
+	for _, path := range files {
+		select {
+		case paths <- path:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(paths)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].Path < results[j].Path })
+	if firstErr != nil {
+		return results, firstErr
+	}
+	return results, ctx.Err()
+}
+
+// isDir reports whether path is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// mergeResults combines per-file optimizers into one for reporting
+func mergeResults(path string, results []*GasOptimizer) *GasOptimizer {
+	if len(results) == 1 {
+		return results[0]
+	}
+	merged := &GasOptimizer{Path: path, Reports: []Report{}}
+	for _, g := range results {
+		merged.Config, merged.Context, merged.MaxReports = g.Config, g.Context, g.MaxReports
+		merged.Reports = append(merged.Reports, g.Reports...)
+		merged.Metrics = append(merged.Metrics, g.Metrics...)
+		merged.Suppressed += g.Suppressed
+	}
+	return merged
+}
+
+// limitReports keeps the first n reports, counting the rest in
+// Suppressed; n <= 0 keeps all. The CLI calls it after sorting, so
+// --max-reports keeps the top findings in the --sort order.
+func (g *GasOptimizer) limitReports(n int) {
+	if n > 0 && len(g.Reports) > n {
+		g.Suppressed += len(g.Reports) - n
+		g.Reports = g.Reports[:n]
+	}
+}
@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestMaxReportsKeepsTopSorted(t *testing.T) {
+	a := &GasOptimizer{Path: "a.sol", Reports: []Report{
+		{RuleID: RuleRedundantExpression, GasSavings: 10, file: "a.sol", line: 1},
+		{RuleID: RuleRedundantExpression, GasSavings: 900, file: "a.sol", line: 2},
+	}}
+	b := &GasOptimizer{Path: "b.sol", Reports: []Report{
+		{RuleID: RuleRedundantExpression, GasSavings: 50, file: "b.sol", line: 1},
+		{RuleID: RuleRedundantExpression, GasSavings: 700, file: "b.sol", line: 2},
+	}}
+	g := mergeResults("a.sol b.sol", []*GasOptimizer{a, b})
+	sortModes["savings"](g.Reports)
+	g.limitReports(2)
+	if len(g.Reports) != 2 || g.Reports[0].GasSavings != 900 || g.Reports[1].GasSavings != 700 {
+		t.Errorf("reports = %+v, want the 900 and 700 gas findings", g.Reports)
+	}
+	if g.Suppressed != 2 {
+		t.Errorf("suppressed = %d, want 2", g.Suppressed)
+	}
+}
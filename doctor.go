@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// doctorContract is compiled to check that solc's AST output parses
+const doctorContract = `// SPDX-License-Identifier: MIT
+pragma solidity >=0.4.0;
+
+contract Doctor {
+    uint x;
+}
+`
+
+// doctorFallbackSource exercises the custom parser's loop detection
+const doctorFallbackSource = `for (i) {
+    s.x;
+    s.x;
+}
+`
+
+// runDoctor implements "gasoptimizer doctor": it checks solc and the
+// fallback parser and reports which one analyze will use
+func runDoctor(args []string) int {
+	solcOK := checkSolc()
+	fallbackOK := checkFallback()
+
+	switch {
+	case solcOK:
+		fmt.Println("=> analyze will use solc")
+	case fallbackOK:
+		fmt.Println("=> analyze will use the fallback parser (loop storage reads only)")
+	default:
+		fmt.Println("=> neither solc nor the fallback parser is usable")
+		return ExitFindings
+	}
+	return ExitOK
+}
+
+// checkSolc verifies solc is on PATH, prints its version and confirms its
+// AST output parses
+func checkSolc() bool {
+	path, err := exec.LookPath("solc")
+	if err != nil {
+		fmt.Println("[FAIL] solc not found on PATH")
+		return false
+	}
+	fmt.Printf("[ OK ] solc found at %s\n", path)
+
+	out, err := exec.Command(path, "--version").CombinedOutput()
+	if err != nil {
+		fmt.Printf("[FAIL] solc --version: %v\n", err)
+		return false
+	}
+	version := strings.TrimSpace(string(out))
+	if i := strings.LastIndex(version, "\n"); i >= 0 {
+		version = version[i+1:]
+	}
+	fmt.Printf("[ OK ] %s\n", version)
+
+	dir, err := os.MkdirTemp("", "gasoptimizer-doctor")
+	if err != nil {
+		fmt.Printf("[FAIL] temp dir: %v\n", err)
+		return false
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "Doctor.sol")
+	if err := os.WriteFile(file, []byte(doctorContract), 0o644); err != nil {
+		fmt.Printf("[FAIL] temp file: %v\n", err)
+		return false
+	}
+	out, err = exec.Command(path, "--ast-compact-json", file).CombinedOutput()
+	if err != nil {
+		fmt.Printf("[FAIL] solc --ast-compact-json: %v\n%s", err, out)
+		return false
+	}
+	if _, err := parseSolcOutput(file, out); err != nil {
+		fmt.Printf("[FAIL] AST output not understood: %v\n", err)
+		return false
+	}
+	fmt.Println("[ OK ] AST output parses")
+	return true
+}
+
+// checkFallback runs the custom parser on a known sample
+func checkFallback() bool {
+	g := &GasOptimizer{Path: "doctor.sol", Source: doctorFallbackSource, AST: NewParser(doctorFallbackSource).Parse()}
+	g.Analyze()
+	if len(g.Reports) != 1 || g.Reports[0].RuleID != RuleLoopStorageRead {
+		fmt.Printf("[FAIL] fallback parser: expected 1 loop finding, got %d\n", len(g.Reports))
+		return false
+	}
+	fmt.Println("[ OK ] fallback parser works")
+	return true
+}
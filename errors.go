@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors identifying what went wrong; match them with errors.Is
+var (
+	ErrReadFile     = errors.New("failed to read file")
+	ErrSolcNotFound = errors.New("solc not found")
+	ErrSolcFailed   = errors.New("solc failed")
+	ErrSolcSkipped  = errors.New("solc skipped")
+	ErrASTParse     = errors.New("failed to parse AST")
+)
+
+// AnalysisError carries the failing path and underlying cause alongside one
+// of the Err* sentinels; retrieve it with errors.As
+type AnalysisError struct {
+	Kind   error  // one of the Err* sentinels
+	Path   string // file being analyzed
+	Detail string // extra context such as tool output
+	Err    error  // underlying cause, may be nil
+}
+
+func (e *AnalysisError) Error() string {
+	msg := fmt.Sprintf("%v: %s", e.Kind, e.Path)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Detail != "" {
+		msg += ", output: " + e.Detail
+	}
+	return msg
+}
+
+// Is matches the error's Kind sentinel
+func (e *AnalysisError) Is(target error) bool {
+	return target == e.Kind
+}
+
+// Unwrap exposes the underlying cause
+func (e *AnalysisError) Unwrap() error {
+	return e.Err
+}
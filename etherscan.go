@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// etherscanAPI is the Etherscan v2 endpoint, which serves every supported
+// chain selected by chainid
+var etherscanAPI = "https://api.etherscan.io/v2/api"
+
+// etherscanTimeout bounds one source fetch
+const etherscanTimeout = 30 * time.Second
+
+// addressPattern matches a hex contract address
+var addressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// etherscanResponse is the envelope of every Etherscan API reply. result is
+// an array on success and an error string otherwise.
+type etherscanResponse struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// etherscanContract is one entry of a getsourcecode result
+type etherscanContract struct {
+	SourceCode   string `json:"SourceCode"`
+	ContractName string `json:"ContractName"`
+}
+
+// etherscanSourceFile is one file of a multi-file submission
+type etherscanSourceFile struct {
+	Content string `json:"content"`
+}
+
+// fetchEtherscanSource downloads the verified source of the contract at
+// address into a new temporary directory, laid out as it was submitted.
+// The caller removes the directory.
+func fetchEtherscanSource(ctx context.Context, address, apiKey string, chainID int) (string, error) {
+	if !addressPattern.MatchString(address) {
+		return "", fmt.Errorf("invalid contract address %q (want 0x followed by 40 hex digits)", address)
+	}
+	if apiKey == "" {
+		return "", errors.New("an Etherscan API key is required (--api-key or ETHERSCAN_API_KEY)")
+	}
+	query := url.Values{
+		"chainid": {strconv.Itoa(chainID)},
+		"module":  {"contract"},
+		"action":  {"getsourcecode"},
+		"address": {address},
+		"apikey":  {apiKey},
+	}
+	ctx, cancel := context.WithTimeout(ctx, etherscanTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, etherscanAPI+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// The URL carries the API key, so report the cause without it
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return "", fmt.Errorf("fetching source from Etherscan: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching source from Etherscan: HTTP %s", resp.Status)
+	}
+	var envelope etherscanResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return "", fmt.Errorf("fetching source from Etherscan: invalid response: %v", err)
+	}
+	var contracts []etherscanContract
+	if envelope.Status != "1" || json.Unmarshal(envelope.Result, &contracts) != nil {
+		var reason string
+		json.Unmarshal(envelope.Result, &reason)
+		return "", fmt.Errorf("Etherscan: %s: %s", envelope.Message, reason)
+	}
+	if len(contracts) == 0 || contracts[0].SourceCode == "" {
+		return "", fmt.Errorf("Etherscan has no verified source for %s on chain %d", address, chainID)
+	}
+	sources, err := etherscanSources(contracts[0])
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "gasoptimizer-etherscan-")
+	if err != nil {
+		return "", err
+	}
+	for name, content := range sources {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err == nil {
+			err = os.WriteFile(path, []byte(content), 0o644)
+		}
+		if err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+// etherscanSources splits a verified submission into files. SourceCode is
+// plain Solidity for single files, a JSON map of files for multi-file
+// submissions, or standard JSON input wrapped in an extra pair of braces.
+func etherscanSources(c etherscanContract) (map[string]string, error) {
+	code := strings.TrimSpace(c.SourceCode)
+	if !strings.HasPrefix(code, "{") {
+		name := c.ContractName
+		if name == "" {
+			name = "Contract"
+		}
+		return map[string]string{name + ".sol": code}, nil
+	}
+	var files map[string]etherscanSourceFile
+	if strings.HasPrefix(code, "{{") {
+		var input struct {
+			Sources map[string]etherscanSourceFile `json:"sources"`
+		}
+		if err := json.Unmarshal([]byte(code[1:len(code)-1]), &input); err != nil {
+			return nil, fmt.Errorf("Etherscan: cannot decode standard JSON source: %v", err)
+		}
+		files = input.Sources
+	} else if err := json.Unmarshal([]byte(code), &files); err != nil {
+		return nil, fmt.Errorf("Etherscan: cannot decode multi-file source: %v", err)
+	}
+	sources := make(map[string]string, len(files))
+	for name, file := range files {
+		clean := filepath.Clean(filepath.FromSlash(name))
+		if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("Etherscan: source path %q escapes the source tree", name)
+		}
+		sources[clean] = file.Content
+	}
+	return sources, nil
+}
+
+// trimResultPaths makes paths under dir relative to it, so reports on
+// fetched sources show the paths the contract was verified with
+func trimResultPaths(results []*GasOptimizer, dir string) {
+	prefix := dir + string(filepath.Separator)
+	for _, g := range results {
+		g.Path = strings.TrimPrefix(g.Path, prefix)
+		for i := range g.Reports {
+			r := &g.Reports[i]
+			r.Location = strings.TrimPrefix(r.Location, prefix)
+			r.file = strings.TrimPrefix(r.file, prefix)
+		}
+	}
+}
@@ -0,0 +1,624 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ruleDoc is the long-form guidance printed by "gasoptimizer explain"
+type ruleDoc struct {
+	Details   string // what the detector looks for
+	Before    string // Solidity that triggers the rule
+	After     string // the suggested rewrite
+	Rationale string // where the gas goes
+	Caveats   string // false positives and behavior changes
+}
+
+// ruleDocs holds the guidance for each rule, keyed by rule ID
+var ruleDocs = map[string]ruleDoc{
+	RuleLoopStorageRead: {
+		Details: "A state variable is read on every iteration of a loop, e.g. an array length in the loop condition or a config value in the body.",
+		Before: `for (uint256 i = 0; i < items.length; i++) {
+    total += items[i] * rate;
+}`,
+		After: `uint256 len = items.length;
+uint256 r = rate;
+for (uint256 i = 0; i < len; i++) {
+    total += items[i] * r;
+}`,
+		Rationale: "Each read is an SLOAD: 2100 gas cold and 100 gas warm, against 3 gas for a stack or memory read. The saving grows with the trip count.",
+		Caveats:   "Only cache values the loop does not modify. If the loop body writes the variable, or calls code that may, the cached copy goes stale.",
+	},
+	RuleInefficientType: {
+		Details: "A uint8 to uint128 variable outside a struct, where it cannot be packed with neighbours.",
+		Before:  `uint8 count;`,
+		After:   `uint256 count;`,
+		Rationale: "The EVM works on 32-byte words, so narrow types are masked on every read and write. " +
+			"The masking only pays off when several narrow values share one storage slot.",
+		Caveats: "Low confidence: consecutive narrow state variables do pack into one slot, and ABI-facing types may need to stay narrow.",
+	},
+	RuleRedundantExpression: {
+		Details: "The same arithmetic expression, up to operand order and parentheses, is computed more than once in a function.",
+		Before: `uint256 fee = amount * rate / 1e4;
+require(balance >= amount * rate / 1e4);`,
+		After: `uint256 fee = amount * rate / 1e4;
+require(balance >= fee);`,
+		Rationale: "Every repetition redoes the arithmetic and its overflow checks, and re-reads any state operands.",
+		Caveats:   "An operand written between the two computations changes the result, so the repetitions are not always redundant.",
+	},
+	RuleRequireString: {
+		Details: "require(cond, \"message\") with a revert string.",
+		Before:  `require(msg.sender == owner, "Ownable: caller is not the owner");`,
+		After: `error NotOwner();
+if (msg.sender != owner) revert NotOwner();`,
+		Rationale: "The string is stored in the bytecode and ABI-encoded on revert. A custom error is a 4-byte selector, which shrinks deployment cost and the revert path.",
+		Caveats:   "Clients and tests that match on the revert string must be updated. The suggestion follows the pragma: require(cond, CustomError()) from 0.8.26, if (!cond) revert CustomError(); from 0.8.4, and below that only messages over 32 bytes are reported, to be shortened.",
+	},
+	RuleConstantCondition: {
+		Details:   "An if or require whose condition is the literal true or false.",
+		Before:    `if (false) { legacyPath(); }`,
+		After:     `// removed`,
+		Rationale: "The dead branch still costs deployment gas, and a require(true) still pays for its check unless the optimizer removes it.",
+		Caveats:   "Constant conditions sometimes mark code disabled on purpose; delete it rather than leaving it unreachable.",
+	},
+	RuleLoopAllocation: {
+		Details: "A memory array or struct allocated with identical arguments on every loop iteration, by new, an array or struct literal, abi.encode* or a copy out of storage.",
+		Before: `for (uint256 i = 0; i < n; i++) {
+    uint256[] memory buf = new uint256[](4);
+    fill(buf, i);
+}`,
+		After: `uint256[] memory buf = new uint256[](4);
+for (uint256 i = 0; i < n; i++) {
+    fill(buf, i);
+}`,
+		Rationale: "Each allocation advances the free memory pointer and zeroes the new memory, and memory expansion cost grows quadratically.",
+		Caveats:   "Hoisting shares one buffer across iterations; clear it explicitly if later iterations rely on it starting zeroed.",
+	},
+	RuleMemoryStructParam: {
+		Details:   "A struct parameter declared memory that the function only reads.",
+		Before:    `function total(Order memory o) external pure returns (uint256)`,
+		After:     `function total(Order calldata o) external pure returns (uint256)`,
+		Rationale: "A memory parameter is copied in full at the call boundary. calldata (external functions) or a storage pointer (internal callers passing storage) reads fields in place.",
+		Caveats:   "calldata is read-only and only reaches external functions directly; internal callers holding memory structs still need a memory parameter.",
+	},
+	RuleRepeatedIndexAccess: {
+		Details: "The same array or mapping element, such as balances[user], read three or more times in a function.",
+		Before: `if (balances[user] > limit) {
+    emit Over(user, balances[user]);
+    total -= balances[user];
+}`,
+		After: `uint256 bal = balances[user];
+if (bal > limit) {
+    emit Over(user, bal);
+    total -= bal;
+}`,
+		Rationale: "Each storage access recomputes the slot (a keccak256 for mappings) and pays an SLOAD; arrays also pay a bounds check.",
+		Caveats:   "The element must not change between the reads. Variables listed in exemptVariables are never reported.",
+	},
+	RuleIncrementInIndex: {
+		Details: "An increment or decrement folded into an index, as in arr[i++].",
+		Before:  `arr[i++] = x;`,
+		After: `arr[i] = x;
+unchecked { ++i; }`,
+		Rationale: "Informational: the post-increment keeps a copy of the old value, and a separate pre-increment can be unchecked when bounded.",
+		Caveats:   "Only unchecked arithmetic saves gas; do it only when the counter provably cannot overflow.",
+	},
+	RuleInlinedModifier: {
+		Details: "A modifier with a substantial body applied to many functions.",
+		Before: `modifier onlyRole(bytes32 role) {
+    require(hasRole(role, msg.sender), "denied");
+    _;
+}`,
+		After: `modifier onlyRole(bytes32 role) {
+    _checkRole(role);
+    _;
+}
+function _checkRole(bytes32 role) internal view {
+    require(hasRole(role, msg.sender), "denied");
+}`,
+		Rationale: "Modifier bodies are copied into every function that uses them, inflating bytecode and deployment cost. An internal function is emitted once.",
+		Caveats:   "Each call then pays an internal jump (about 20-40 gas) at runtime, trading runtime gas for deployment gas.",
+	},
+	RuleExternalSelfCall: {
+		Details:   "this.f() calling a function of the same contract.",
+		Before:    `uint256 p = this.price(token);`,
+		After:     `uint256 p = price(token); // make price public or internal`,
+		Rationale: "this.f() is a full external CALL with ABI encoding and decoding and the callee's dispatch; an internal call is a jump.",
+		Caveats:   "msg.sender and msg.value differ between the two forms, and try/catch around this.f() relies on the external call.",
+	},
+	RuleToggledBoolFlag: {
+		Details: "A bool state variable that is set and cleared in the same contract, like a reentrancy lock.",
+		Before: `bool private locked;
+locked = true; _; locked = false;`,
+		After: `uint256 private locked = 1;
+locked = 2; _; locked = 1;`,
+		Rationale: "Writing zero to a slot and later setting it non-zero costs 20000 gas. Toggling between two non-zero values costs 2900 instead.",
+		Caveats:   "With transient storage (EIP-1153) a lock is cheaper still. Change the initial value too, or the first write still pays the zero-to-non-zero price.",
+	},
+	RuleUnrollableLoop: {
+		Details: "A loop with a small literal trip count.",
+		Before: `for (uint256 i = 0; i < 3; i++) {
+    sum += vals[i];
+}`,
+		After:     `sum += vals[0] + vals[1] + vals[2];`,
+		Rationale: "Unrolling removes the counter increment, bound check and jump on every iteration.",
+		Caveats:   "Unrolled code is larger, raising deployment cost, and easier to get wrong when the bound changes.",
+	},
+	RuleManyReturnValues: {
+		Details:   "A function returning four or more values, several of them narrower than 32 bytes.",
+		Before:    `function info() external view returns (uint8, uint16, uint32, address)`,
+		After:     `function info() external view returns (Info memory)`,
+		Rationale: "Each return value is a stack item, and narrow values are cleaned individually. A struct is one memory pointer.",
+		Caveats:   "Low confidence: returning a struct changes the ABI and can cost more for external callers that decode it.",
+	},
+	RuleSafeMathOnChecked: {
+		Details:   "Calls such as a.add(b) into a library named SafeMath, or OpenZeppelin's SafeMath or SignedSafeMath, when every version the pragma allows is 0.8.0 or later.",
+		Before:    `total = total.add(amount);`,
+		After:     `total = total + amount;`,
+		Rationale: "Solidity 0.8 checks arithmetic itself, so SafeMath adds an internal call and a second, duplicate check.",
+		Caveats:   "SafeMath's sub and div with messages revert with a string rather than a Panic; callers matching on it must be updated.",
+	},
+	RuleNewInLoop: {
+		Details: "A contract deployed with new inside a loop.",
+		Before: `for (uint256 i = 0; i < n; i++) {
+    vaults.push(new Vault(owners[i]));
+}`,
+		After: `for (uint256 i = 0; i < n; i++) {
+    vaults.push(Clones.clone(vaultImpl)); // then initialize
+}`,
+		Rationale: "Each CREATE costs 32000 gas plus 200 gas per byte of deployed code. A minimal proxy (EIP-1167) deploys 45 bytes.",
+		Caveats:   "Clones delegate to one implementation, so constructors become initializers and every call pays a DELEGATECALL.",
+	},
+	RuleEncodeWithSignature: {
+		Details:   "abi.encodeWithSignature with a literal signature string.",
+		Before:    `abi.encodeWithSignature("transfer(address,uint256)", to, amount)`,
+		After:     `abi.encodeCall(IERC20.transfer, (to, amount))`,
+		Rationale: "The signature is hashed with keccak256 at runtime. encodeWithSelector or encodeCall uses a constant selector and type-checks the arguments.",
+		Caveats:   "encodeCall needs an interface declaring the function.",
+	},
+	RuleRepeatedHashKey: {
+		Details: "The same keccak256 key computed more than once in a function.",
+		Before: `data[keccak256(abi.encode(a, b))] += x;
+emit Set(keccak256(abi.encode(a, b)));`,
+		After: `bytes32 key = keccak256(abi.encode(a, b));
+data[key] += x;
+emit Set(key);`,
+		Rationale: "Each computation re-encodes the inputs into memory and hashes them: 30 gas plus 6 per word, plus the memory allocation.",
+		Caveats:   "The inputs must not change between the computations.",
+	},
+	RuleMissingMutability: {
+		Details:   "A function that never writes state is not declared view, or one that never reads state is not declared pure.",
+		Before:    `function fee(uint256 x) public returns (uint256) { return x / 100; }`,
+		After:     `function fee(uint256 x) public pure returns (uint256) { return x / 100; }`,
+		Rationale: "Calls to view and pure functions from other contracts use STATICCALL, and the compiler can skip checks for non-payable state access.",
+		Caveats:   "Functions meant to be overridden by state-changing implementations must stay non-view.",
+	},
+	RuleYulRepeatedSload: {
+		Details: "In Yul, sload of the same slot more than once with no sstore in between.",
+		Before: `let a := sload(0)
+let b := add(sload(0), 1)`,
+		After: `let s := sload(0)
+let a := s
+let b := add(s, 1)`,
+		Rationale: "Every sload pays at least 100 gas; a let variable is a stack read.",
+		Caveats:   "Calls to user functions or external contracts may write the slot, so loads across them are not reported.",
+	},
+	RuleYulRedundantMstore: {
+		Details: "In Yul, an mstore to an offset that is overwritten before anything reads memory.",
+		Before: `mstore(0, a)
+mstore(0, b)
+return(0, 32)`,
+		After: `mstore(0, b)
+return(0, 32)`,
+		Rationale: "The first store is dead: its MSTORE and argument evaluation are wasted.",
+		Caveats:   "Stores to overlapping but different offsets are not tracked, so only exact offset matches are reported.",
+	},
+	RuleLoopConstantRead: {
+		Details: "A constant or immutable read inside a loop.",
+		Before: `for (uint256 i = 0; i < n; i++) {
+    total += amounts[i] * FEE;
+}`,
+		After:     `// no change needed`,
+		Rationale: "Informational: constants and immutables are inlined as PUSH instructions, so unlike state variables they cost no SLOAD.",
+		Caveats:   "Nothing to fix; the finding confirms the read is already cheap.",
+	},
+	RuleRepeatedExternal: {
+		Details: "The same external view call, with the same arguments, made more than once in a function.",
+		Before: `if (token.balanceOf(user) > 0) {
+    amount = token.balanceOf(user);
+}`,
+		After: `uint256 bal = token.balanceOf(user);
+if (bal > 0) {
+    amount = bal;
+}`,
+		Rationale: "Each external call pays for the CALL, ABI encoding and decoding and the callee's own work, hundreds of gas at least.",
+		Caveats:   "A state-changing call between the two, or a callee whose result depends on block data, can make the results differ.",
+	},
+	RuleDuplicateZeroCheck: {
+		Details: "The same address compared with address(0) more than once in a function.",
+		Before: `require(to != address(0));
+// ...
+require(to != address(0), "zero");`,
+		After:     `require(to != address(0));`,
+		Rationale: "The repeated check evaluates the comparison and a JUMPI again.",
+		Caveats:   "Checks in different branches may both be needed; only remove checks that always run after the first.",
+	},
+	RuleMissingZeroCheck: {
+		Details: "An address parameter receives ether or tokens without being checked against address(0). Off by default.",
+		Before: `function withdraw(address to) external {
+    payable(to).transfer(balance);
+}`,
+		After: `function withdraw(address to) external {
+    if (to == address(0)) revert ZeroAddress();
+    payable(to).transfer(balance);
+}`,
+		Rationale: "A safety rule, not a gas rule: funds sent to address(0) are lost. The check costs gas.",
+		Caveats:   "Callers may already guarantee a non-zero address, and some tokens revert on transfers to address(0) themselves.",
+	},
+	RuleMappingExistence: {
+		Details: "A mapping value compared with 0 to decide whether a key exists.",
+		Before:  `require(balances[user] == 0, "already registered");`,
+		After: `mapping(address => bool) registered;
+require(!registered[user], "already registered");`,
+		Rationale: "Correctness, not gas: a key that was set to 0 looks the same as one that was never set.",
+		Caveats:   "Fine where 0 can never be a valid stored value; low confidence for that reason.",
+	},
+	RuleLoopStorageWrite: {
+		Details: "A storage array element written on every loop iteration.",
+		Before: `for (uint256 i = 0; i < n; i++) {
+    results[i] = compute(i);
+}`,
+		After: `uint256[] memory tmp = new uint256[](n);
+for (uint256 i = 0; i < n; i++) {
+    tmp[i] = compute(i);
+}
+results = tmp;`,
+		Rationale: "Each element write pays an SSTORE plus the length check and slot computation; a memory array is filled with MSTOREs.",
+		Caveats:   "The bulk assignment still pays one SSTORE per element; the saving is the per-iteration overhead, and it only applies when the array is rebuilt.",
+	},
+	RuleBoundedLoop: {
+		Details: "A loop counter compared against a variable that a preceding require caps at a literal or constant, under a pragma older than 0.8.22.",
+		Before: `require(n <= 50);
+for (uint256 i = 0; i < n; i++) { ... }`,
+		After: `require(n <= 50);
+for (uint256 i = 0; i < n;) {
+    ...
+    unchecked { ++i; }
+}`,
+		Rationale: "The cap proves the increment cannot overflow, so its checked arithmetic is wasted on every iteration. Solidity 0.8.22 removes it automatically.",
+		Caveats:   "Code that continues inside the loop must still reach the increment.",
+	},
+	RuleLargeEventData: {
+		Details:   "An event that logs bytes or string data as non-indexed parameters.",
+		Before:    `event Stored(uint256 id, bytes payload);`,
+		After:     `event Stored(uint256 id, bytes32 payloadHash);`,
+		Rationale: "LOG charges 8 gas per data byte, so large payloads add up; a 32-byte hash has a fixed cost.",
+		Caveats:   "Off-chain consumers that need the payload must then get it elsewhere, e.g. from calldata.",
+	},
+	RuleSplitStructWrite: {
+		Details: "Consecutive writes to fields of a storage struct that share one storage slot.",
+		Before: `pos.amount = a;
+pos.since = uint64(block.timestamp);`,
+		After:     `positions[id] = Position({amount: a, since: uint64(block.timestamp), owner: pos.owner});`,
+		Rationale: "Each field write reads, masks and writes the whole slot. A combined write touches the slot once.",
+		Caveats:   "Low confidence: the optimizer often merges adjacent writes itself, and the struct literal must carry over every other field of the slot.",
+	},
+	RuleConversionRoundTrip: {
+		Details:   "string(bytes(x)) where x is already a string, or bytes(string(x)) where x is already bytes.",
+		Before:    `emit Named(string(bytes(name)));`,
+		After:     `emit Named(name);`,
+		Rationale: "Both conversions are no-ops in memory but still cost stack shuffling and, for calldata, a copy.",
+		Caveats:   "None known.",
+	},
+	RuleUnusedParameter: {
+		Details:   "A named parameter never referenced in the function body or its modifiers.",
+		Before:    `function hook(address from, uint256 amount) internal { emit Hooked(from); }`,
+		After:     `function hook(address from, uint256 /* amount */) internal { emit Hooked(from); }`,
+		Rationale: "External functions still decode and validate the argument; internal functions keep it on the stack. Leaving it unnamed documents that it is unused.",
+		Caveats:   "Virtual and overriding functions are skipped because their signatures are fixed. Removing a parameter of an external function changes its selector.",
+	},
+	RuleRepeatedCodeLength: {
+		Details: "The same address checked with .code.length or extcodesize more than once in a function.",
+		Before: `require(target.code.length > 0);
+// ...
+if (target.code.length == 0) revert();`,
+		After: `uint256 size = target.code.length;
+require(size > 0);`,
+		Rationale: "Each check is an EXTCODESIZE, 100 gas warm and 2600 cold.",
+		Caveats:   "A contract deployed or self-destructed in between changes the size, which matters only across external calls.",
+	},
+	RuleModularArithmetic: {
+		Details:   "(a * b) % n or (a + b) % n on uint256.",
+		Before:    `uint256 r = (a * b) % p;`,
+		After:     `uint256 r = mulmod(a, b, p);`,
+		Rationale: "The builtins skip the overflow check and compute with 512-bit intermediate precision.",
+		Caveats:   "Where a * b overflows, checked code reverts and unchecked code wraps, but mulmod returns the exact result. Make sure no caller relies on the revert.",
+	},
+	RuleConstructorOnly: {
+		Details: "A state variable assigned in the constructor and nowhere else.",
+		Before: `address public treasury;
+constructor(address t) { treasury = t; }`,
+		After: `address public immutable treasury;
+constructor(address t) { treasury = t; }`,
+		Rationale: "An immutable is embedded in the bytecode, so each read is a PUSH instead of an SLOAD of at least 100 gas.",
+		Caveats:   "Only value types can be immutable; reference types are reported as set-once state. Contracts with inline assembly outside the constructor are skipped.",
+	},
+	RuleRecomputedReturn: {
+		Details: "A function assigns an expression to a state variable and then returns the same expression, recomputed.",
+		Before: `total = a + b;
+return a + b;`,
+		After: `uint256 sum = a + b;
+total = sum;
+return sum;`,
+		Rationale: "The return redoes the arithmetic and re-reads any state operands. Returning the state variable would cost an SLOAD instead, so use a local.",
+		Caveats:   "Calls and assembly between the two statements end the match, since they may change the operands.",
+	},
+	RuleLengthBeforeIndex: {
+		Details: "require(i < arr.length) on a dynamic storage array directly followed by a statement reading arr[i].",
+		Before: `require(i < items.length, "bad index");
+Item storage it = items[i];`,
+		After:     `Item storage it = items[i]; // reverts with Panic(0x32) when out of bounds`,
+		Rationale: "The index access loads the length again for its own bounds check, so the require's SLOAD of the length is paid twice.",
+		Caveats:   "Dropping the require changes the revert from its message to a Panic. Keep it if callers rely on the message, and cache the length if it is used again.",
+	},
+	RuleEmitBeforeWrite: {
+		Details: "An event argument reads a state variable that a later statement in the same block writes.",
+		Before: `emit Deposited(user, balances[user]);
+balances[user] += amount;`,
+		After: `balances[user] += amount;
+emit Deposited(user, balances[user]);`,
+		Rationale: "Correctness, not gas: the event logs the value from before the update, which indexers may read as the new value.",
+		Caveats:   "Events that deliberately log the old value next to the new one, like emit OwnerChanged(owner, newOwner), are skipped when the event carries the assigned value.",
+	},
+	RuleNonNegativeInt: {
+		Details: "An int variable whose initial value, assignments and comparisons only involve non-negative literals, and which is never decremented or negated.",
+		Before: `int256 attempts;
+attempts += 1;
+if (attempts > 3) revert TooMany();`,
+		After: `uint256 attempts;
+attempts += 1;
+if (attempts > 3) revert TooMany();`,
+		Rationale: "Checked signed arithmetic needs extra sign handling in its overflow checks, and signed division and comparison use the signed opcode variants.",
+		Caveats:   "Low confidence: reads passed to functions or mixed into signed expressions are not traced, and changing the type can break those uses or an interface.",
+	},
+	RuleUnsignedZeroCompare: {
+		Details: "x >= 0 or 0 <= x, which always hold, and x < 0 or 0 > x, which never do, where x is an unsigned integer.",
+		Before: `require(amount >= 0, "negative amount");
+if (index < 0) revert OutOfRange();`,
+		After:     `// both checks removed; use amount > 0 if zero must be rejected`,
+		Rationale: "The comparison and its JUMPI run on every call without ever changing the outcome. Usually it is also a bug: the intended check was > 0, or the value was meant to be signed.",
+		Caveats:   "None for the comparison itself, but check whether the code meant something stricter before deleting it.",
+	},
+	RuleSharedPreamble: {
+		Details: "Two or more public or external functions of a contract whose leading require or assert statements are identical.",
+		Before: `function deposit(uint256 amount) external {
+    require(!paused, "paused");
+    require(amount > 0, "zero");
+    ...
+}
+function stake(uint256 amount) external {
+    require(!paused, "paused");
+    require(amount > 0, "zero");
+    ...
+}`,
+		After: `function _checkAmount(uint256 amount) internal view {
+    require(!paused, "paused");
+    require(amount > 0, "zero");
+}`,
+		Rationale: "Every copy of the checks is compiled into the bytecode, raising deployment cost. One internal helper is emitted once; if the functions are always called together, a combined entry point also runs the checks once.",
+		Caveats:   "Low confidence: the helper adds an internal jump per call, and a modifier would not help since modifiers are inlined too. Checks matched by text may refer to different variables of the same name.",
+	},
+	RuleRepeatedDecode: {
+		Details: "abi.decode called more than once in a function with the same payload and the same types.",
+		Before: `(address to, uint256 amount) = abi.decode(data, (address, uint256));
+...
+(, uint256 again) = abi.decode(data, (address, uint256));
+total += again;`,
+		After: `(address to, uint256 amount) = abi.decode(data, (address, uint256));
+...
+total += amount;`,
+		Rationale: "Each decode reads and validates every word of the payload again, and decoding dynamic types copies them into a fresh memory allocation.",
+		Caveats:   "The payload must not change between the decodes. Payloads are matched by name, so a slice such as data[4:] is never compared.",
+	},
+	RuleGasStipend: {
+		Details:   "A call whose {gas: ...} option is a number literal.",
+		Before:    `(bool ok, ) = to.call{value: amount, gas: 2300}("");`,
+		After:     `(bool ok, ) = to.call{value: amount}(""); // with a reentrancy guard`,
+		Rationale: "Opcode costs change between hard forks, so a fixed budget that suffices today can make the callee run out of gas later, as happened to transfer() and send() when EIP-1884 repriced SLOAD. Too large a budget forwards gas the caller needed.",
+		Caveats:   "A stipend may be a deliberate reentrancy defence or bound on a callback; if so, keep it and document the reasoning.",
+	},
+	RuleStoragePointer: {
+		Details: "A local storage pointer such as Position storage p = positions[id] that is never used afterwards, or alongside which the code indexes positions[id] directly again.",
+		Before: `Position storage p = positions[id];
+positions[id].amount += x;
+positions[id].updated = block.timestamp;`,
+		After: `Position storage p = positions[id];
+p.amount += x;
+p.updated = block.timestamp;`,
+		Rationale: "Setting up the pointer computes the element's slot: a keccak256 for a mapping key, a bounds check for an array. Indexing the element directly computes it again each time, and an unused pointer computes it for nothing.",
+		Caveats:   "Low confidence: the optimizer can sometimes share the slot computation, and the direct accesses may be meant to read a different element if the index changes in between (such cases are skipped when the index is reassigned).",
+	},
+	RuleUncheckedArithmetic: {
+		Details: "An increment or decrement by one of an unsigned local that cannot overflow or underflow: it follows a require capping the value below a literal or constant (or requiring it to be non-zero, for decrements), or it is i + 1 inside a for loop running while i < n.",
+		Before: `require(count < MAX_ITEMS, "full");
+count++;`,
+		After: `require(count < MAX_ITEMS, "full");
+unchecked { count++; }`,
+		Rationale: "Since Solidity 0.8 every + and - carries an overflow check, about 30 gas each, which cannot fail here. Loop counter steps in the for header are covered by require-bounded-loop (GAS028).",
+		Caveats:   "Only the first increment after the require is flagged, since it uses up the headroom, and loops that step the value are skipped. Check the bound is still in force if the code is reordered later.",
+	},
+	RuleBlockNumberTiming: {
+		Details:   "Arithmetic combining block.number with a literal that looks like an assumed block time (12 to 15 seconds) or a number of blocks per hour, day, week or year.",
+		Before:    `unlockBlock = block.number + 7200; // one day`,
+		After:     `unlockTime = block.timestamp + 1 days;`,
+		Rationale: "Block times are not fixed: they changed when Ethereum moved to proof of stake and differ on every L2 and sidechain, so durations in blocks drift. block.timestamp costs the same 2 gas as block.number and converting to seconds needs no extra arithmetic.",
+		Caveats:   "Low confidence: the literal may be unrelated to time, and some protocols deliberately count blocks, e.g. for governance voting periods.",
+	},
+	RuleStorageArrayNew: {
+		Details:   "A storage array assigned a new memory array, as in arr = new uint256[](0) or arr = new address[](n).",
+		Before:    `pending = new uint256[](0);`,
+		After:     `delete pending;`,
+		Rationale: "The right-hand side is allocated and zeroed in memory, then copied element by element into storage, which also clears the old elements beyond the new length. delete clears the stored elements and length directly. With a non-zero length, every element is written as a zero SSTORE.",
+		Caveats:   "With a non-zero length the assignment also sets the new length, which delete alone does not do; the replacement needs pushes or a different data layout.",
+	},
+	RuleEmptyLoop: {
+		Details: "A for, while or do-while loop whose body is empty or contains nothing observable: no assignments, calls, events, assembly, return, break or revert.",
+		Before: `for (uint256 i = 0; i < holders.length; i++) {
+    uint256 balance = balances[holders[i]];
+}`,
+		After: `for (uint256 i = 0; i < holders.length; i++) {
+    total += balances[holders[i]];
+}`,
+		Rationale: "The loop pays its condition check, counter step, jump and any reads on every iteration while computing nothing that survives it. Almost always a statement was lost or never written, as with the missing accumulation above.",
+		Caveats:   "For loops stepping a counter declared before the loop are skipped, since an empty-bodied search loop leaves its result in the counter. Loops whose condition calls a function or assigns are skipped too.",
+	},
+	RuleLoopMappingWrite: {
+		Details: "An assignment, compound assignment or increment of a storage mapping entry inside a loop, where the key is not changed by the loop, so every iteration writes the same slot.",
+		Before: `for (uint256 i = 0; i < amounts.length; i++) {
+    balances[user] += amounts[i];
+}`,
+		After: `uint256 total = balances[user];
+for (uint256 i = 0; i < amounts.length; i++) {
+    total += amounts[i];
+}
+balances[user] = total;`,
+		Rationale: "Only the first SSTORE to a slot in a transaction is expensive, but each further write to the now dirty slot still costs a warm SSTORE, and a compound assignment adds a warm SLOAD. A local accumulator replaces them with stack operations and a single write after the loop.",
+		Caveats:   "Calls in the loop that read the mapping, directly or through reentrancy, would see the stale stored value after the change. Keys computed by calls or indexing are assumed to vary and are not reported.",
+	},
+	RuleConstructorParam: {
+		Details: "A value-type state variable that its contract's constructor assigns from a constructor parameter, in a top-level statement, and that no other function, modifier or statement writes.",
+		Before: `address public owner;
+constructor(address _owner) { owner = _owner; }`,
+		After: `address public immutable owner;
+constructor(address _owner) { owner = _owner; }`,
+		Rationale: "An immutable is embedded in the bytecode, so each read is a PUSH instead of an SLOAD of at least 100 gas. A plain copy of a parameter in the constructor is exactly how immutables are set, so the change is safe as is.",
+		Caveats:   "Below Solidity 0.8.21 immutables cannot be read in the constructor, so variables the constructor also reads are left to GAS035 there. Such variables are reported here instead of under GAS035 while this rule is enabled.",
+	},
+	RuleBareRevert: {
+		Details: "A revert() call or require(false) without a message in a public or external function.",
+		Before: `function withdraw() external {
+    if (msg.sender != owner) revert();
+}`,
+		After: `error NotOwner();
+
+function withdraw() external {
+    if (msg.sender != owner) revert NotOwner();
+}`,
+		Rationale: "Advisory rather than a saving: an empty revert is the cheapest failure, but callers, frontends and explorers only see that the call failed. A custom error adds just its 4-byte selector to the revert data and names the failure, where a revert string would cost more.",
+		Caveats:   "Custom errors need Solidity 0.8.4, so sources with an older pragma are skipped. Reverts in internal functions are not reported, although they reach callers of public functions too.",
+	},
+	RuleVariableLoopStep: {
+		Details: "A for loop whose update step reads a state variable that nothing in the loop writes, as in i += step or i = i + step.",
+		Before: `uint256 public step = 4;
+
+for (uint256 i = 0; i < n; i += step) { ... }`,
+		After: `uint256 public constant STEP = 4;
+
+for (uint256 i = 0; i < n; i += STEP) { ... }`,
+		Rationale: "The update runs on every iteration, and each run reads the step from storage: a warm SLOAD of 100 gas after the first. A local copy is read from the stack; a constant is inlined.",
+		Caveats:   "constant is suggested only when the variable is initialized with a number literal in the analyzed file and never written; otherwise cache it in a local. A step changed by a function the loop calls would no longer be picked up.",
+	},
+	RuleExpensiveView: {
+		Details: "A public or external view function containing a loop that reads state variables. The estimated cost of one call is shown in the issue.",
+		Before: `function totalStaked() external view returns (uint256 sum) {
+    for (uint256 i = 0; i < stakers.length; i++) {
+        sum += stakes[stakers[i]];
+    }
+}`,
+		After: `function totalStaked(uint256 offset, uint256 limit) external view returns (uint256 sum) {
+    uint256 end = offset + limit;
+    if (end > stakers.length) end = stakers.length;
+    for (uint256 i = offset; i < end; i++) {
+        sum += stakes[stakers[i]];
+    }
+}`,
+		Rationale: "Informational, no saving is claimed: eth_call from off-chain costs nothing, which hides that another contract calling the function pays for every SLOAD of every iteration, and the cost grows with the stored data. A running total kept on writes, or pagination, bounds it.",
+		Caveats:   "The estimate counts state reads of the outermost loops once per iteration, so nested loops are undercounted, and it assumes the configured iteration count where the bound is not a literal. Functions never meant to be called on-chain can ignore it.",
+	},
+	RuleBranchStorageRead: {
+		Details: "A state variable of value type read in both the if and the else branch, with neither branch writing it before the read. Else-if chains count as one statement.",
+		Before: `if (amount > limit) {
+    fee = amount * rate / 100;
+} else {
+    fee = rate;
+}`,
+		After: `uint256 r = rate;
+if (amount > limit) {
+    fee = amount * r / 100;
+} else {
+    fee = r;
+}`,
+		Rationale: "Only one branch runs, so each path reads the variable once either way; hoisting the read merges the two SLOAD sequences into one and shrinks the deployed code. When the condition reads the variable too, every path reads it twice, and a local saves a warm SLOAD of 100 gas at runtime.",
+		Caveats:   "Writes by called functions between the hoisted read and the branch are not detected. Without a condition read the saving is code size only, so the finding reports no runtime gas.",
+	},
+}
+
+// runExplain implements "gasoptimizer explain <rule>"
+func runExplain(args []string) int {
+	fs := flag.NewFlagSet("explain", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gasoptimizer explain <rule ID or name>")
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return ExitUsage
+	}
+	rule, ok := lookupRule(fs.Arg(0))
+	if !ok {
+		log.Printf("Error: unknown rule %q (see 'gasoptimizer rules')", fs.Arg(0))
+		return ExitUsage
+	}
+	writeExplanation(os.Stdout, rule)
+	return ExitOK
+}
+
+// lookupRule finds a rule by ID, case-insensitively, or by name
+func lookupRule(key string) (Rule, bool) {
+	for _, r := range Rules {
+		if strings.EqualFold(r.ID, key) || r.Name == key {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// writeExplanation prints a rule's registry entry and its guidance
+func writeExplanation(w io.Writer, r Rule) {
+	level := LevelWarn
+	if r.Disabled {
+		level = LevelOff
+	}
+	fmt.Fprintf(w, "%s %s\n", r.ID, r.Name)
+	fmt.Fprintf(w, "Category: %s, area: %s, severity: %s, confidence: %s, effort: %s, default: %s\n\n", r.Category, r.Area, r.Severity, r.Confidence, r.Effort, level)
+	fmt.Fprintln(w, r.Description)
+	doc, ok := ruleDocs[r.ID]
+	if !ok {
+		return
+	}
+	for _, section := range []struct{ title, text string }{
+		{"", doc.Details},
+		{"Before:", doc.Before},
+		{"After:", doc.After},
+		{"Why:", doc.Rationale},
+		{"Caveats:", doc.Caveats},
+	} {
+		fmt.Fprintln(w)
+		if section.title == "" {
+			fmt.Fprintln(w, section.text)
+			continue
+		}
+		fmt.Fprintln(w, section.title)
+		for _, line := range strings.Split(section.text, "\n") {
+			fmt.Fprintln(w, "    "+line)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// failOperators are the comparisons a --fail-on term accepts, longest first
+// so that ">=" is not read as ">"
+var failOperators = []string{">=", "<=", "!=", "==", ">", "<", "="}
+
+// failFieldRanks orders the values of the ordered report fields
+var failFieldRanks = map[string]map[string]int{
+	"severity":   {"info": 1, "low": 2, "medium": 3, "high": 4},
+	"confidence": {"low": 1, "medium": 2, "high": 3},
+	"effort":     {"trivial": 1, "moderate": 2, "high": 3},
+	"level":      {"off": 1, "warn": 2, "error": 3},
+}
+
+// failTerm is one comparison of a report field against a value
+type failTerm struct {
+	field string
+	op    string
+	value string
+	rank  int // value's position for ordered fields and savings
+}
+
+// failPredicate decides whether a report fails the run: every term must hold
+type failPredicate []failTerm
+
+// parseFailOn parses a --fail-on expression: comma-separated terms such as
+// "severity>=high,confidence>=medium", all of which must hold. Fields are
+// severity, confidence, effort and level (compared by rank), savings
+// (compared as a number), and rule and category (== and != only; rule
+// accepts globs).
+func parseFailOn(expr string) (failPredicate, error) {
+	var pred failPredicate
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		term, err := parseFailTerm(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --fail-on term %q: %v", part, err)
+		}
+		pred = append(pred, term)
+	}
+	if len(pred) == 0 {
+		return nil, fmt.Errorf("empty --fail-on expression")
+	}
+	return pred, nil
+}
+
+// parseFailTerm parses one "field op value" comparison
+func parseFailTerm(s string) (failTerm, error) {
+	var term failTerm
+	for _, op := range failOperators {
+		if field, value, ok := strings.Cut(s, op); ok {
+			term = failTerm{field: strings.TrimSpace(field), op: op, value: strings.TrimSpace(value)}
+			break
+		}
+	}
+	if term.op == "" {
+		return term, fmt.Errorf("want field, operator (%s) and value", strings.Join(failOperators, " "))
+	}
+	if term.op == "=" {
+		term.op = "=="
+	}
+	ordered := term.op != "==" && term.op != "!="
+	switch ranks, ok := failFieldRanks[term.field]; {
+	case ok:
+		if term.rank = ranks[term.value]; term.rank == 0 {
+			return term, fmt.Errorf("unknown %s %q", term.field, term.value)
+		}
+	case term.field == "savings":
+		n, err := strconv.Atoi(term.value)
+		if err != nil {
+			return term, fmt.Errorf("savings must be a number")
+		}
+		term.rank = n
+	case term.field == "rule":
+		if _, err := path.Match(term.value, ""); err != nil {
+			return term, err
+		}
+		fallthrough
+	case term.field == "category":
+		if ordered {
+			return term, fmt.Errorf("%s only supports == and !=", term.field)
+		}
+	default:
+		return term, fmt.Errorf("unknown field %q", term.field)
+	}
+	return term, nil
+}
+
+// Match reports whether r satisfies every term
+func (p failPredicate) Match(r Report) bool {
+	for _, term := range p {
+		if !term.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// match evaluates the term against r
+func (t failTerm) match(r Report) bool {
+	var value int
+	switch t.field {
+	case "rule":
+		ok, _ := path.Match(t.value, r.RuleID)
+		return ok == (t.op == "==")
+	case "category":
+		return (string(r.Category) == t.value) == (t.op == "==")
+	case "savings":
+		value = r.GasSavings
+	case "severity":
+		value = failFieldRanks[t.field][string(r.Severity)]
+	case "confidence":
+		value = failFieldRanks[t.field][string(r.Confidence)]
+	case "effort":
+		value = failFieldRanks[t.field][string(r.Effort)]
+	case "level":
+		value = failFieldRanks[t.field][string(r.Level)]
+	}
+	switch t.op {
+	case "==":
+		return value == t.rank
+	case "!=":
+		return value != t.rank
+	case ">=":
+		return value >= t.rank
+	case "<=":
+		return value <= t.rank
+	case ">":
+		return value > t.rank
+	}
+	return value < t.rank
+}
+
+// anyMatch reports whether any report satisfies p
+func (p failPredicate) anyMatch(reports []Report) bool {
+	for _, r := range reports {
+		if p.Match(r) {
+			return true
+		}
+	}
+	return false
+}
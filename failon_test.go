@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestFailOnPredicates(t *testing.T) {
+	report := Report{
+		RuleID:     RuleLoopStorageRead,
+		Category:   CategoryGas,
+		Severity:   SeverityMedium,
+		Confidence: ConfidenceHigh,
+		Effort:     EffortTrivial,
+		Level:      LevelWarn,
+		GasSavings: 2100,
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"severity>=medium", true},
+		{"severity>=high", false},
+		{"severity>medium", false},
+		{"severity<high", true},
+		{"severity=medium", true},
+		{"severity!=medium", false},
+		{"confidence>=medium", true},
+		{"confidence<=low", false},
+		{"effort==trivial", true},
+		{"level>=error", false},
+		{"savings>2000", true},
+		{"savings>=2100", true},
+		{"savings<2100", false},
+		{"rule==GAS001", true},
+		{"rule==GAS0*", true},
+		{"rule!=GAS00?", false},
+		{"category==gas", true},
+		{"category!=gas", false},
+		{"severity>=medium, confidence>=high", true},
+		{"severity>=medium,savings>5000", false},
+	}
+	for _, tt := range tests {
+		pred, err := parseFailOn(tt.expr)
+		if err != nil {
+			t.Errorf("parseFailOn(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := pred.Match(report); got != tt.want {
+			t.Errorf("%q matches = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestFailOnInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		" , ",
+		"severity",
+		"severity>=critical",
+		"colour==red",
+		"savings>=lots",
+		"rule>=GAS001",
+		"category<gas",
+		"rule==[",
+	} {
+		if _, err := parseFailOn(expr); err == nil {
+			t.Errorf("parseFailOn(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestFailOnAnyMatch(t *testing.T) {
+	pred, err := parseFailOn("severity>=high")
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, high := Report{Severity: SeverityLow}, Report{Severity: SeverityHigh}
+	if pred.anyMatch([]Report{low, low}) {
+		t.Error("anyMatch without a high finding = true, want false")
+	}
+	if !pred.anyMatch([]Report{low, high}) {
+		t.Error("anyMatch with a high finding = false, want true")
+	}
+}
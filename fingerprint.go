@@ -0,0 +1,49 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// issuePositions matches what in an issue text could be a position: a
+// solc span such as 120:15:0 or a line reference such as "line 42"
+var issuePositions = regexp.MustCompile(`\b\d+:\d+(:\d+)?\b|\bline \d+\b`)
+
+// Fingerprint identifies the finding across runs: a hash of the rule ID,
+// the file, the enclosing function and the issue text with positions
+// removed. Lines are deliberately left out, so code inserted above a
+// finding keeps its fingerprint, while edits to the function's signature
+// or to what the issue names, such as a variable, change it.
+func (r Report) Fingerprint() string {
+	return r.fingerprint(r.file)
+}
+
+// fingerprint is Fingerprint with the file given, for findings of the same
+// code analyzed at another path
+func (r Report) fingerprint(file string) string {
+	issue := strings.Join(strings.Fields(issuePositions.ReplaceAllString(r.Issue, "")), " ")
+	sum := sha256.Sum256([]byte(r.RuleID + "\x00" + file + "\x00" + r.function + "\x00" + issue))
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprints returns the Fingerprint of every report. Identical findings
+// in one function, such as the same pattern twice, are numbered in report
+// order so each keeps a distinct fingerprint.
+func fingerprints(reports []Report) []string {
+	out := make([]string, len(reports))
+	seen := make(map[string]int)
+	for i, r := range reports {
+		fp := r.Fingerprint()
+		n := seen[fp]
+		seen[fp]++
+		if n > 0 {
+			sum := sha256.Sum256([]byte(fp + "\x00" + strconv.Itoa(n)))
+			fp = hex.EncodeToString(sum[:])
+		}
+		out[i] = fp
+	}
+	return out
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// shiftSrc moves every solc span in a JSON AST forward by n bytes
+func shiftSrc(ast string, n int) string {
+	src := regexp.MustCompile(`"src":"(\d+):`)
+	return src.ReplaceAllStringFunc(ast, func(m string) string {
+		start, _ := strconv.Atoi(src.FindStringSubmatch(m)[1])
+		return `"src":"` + strconv.Itoa(start+n) + `:`
+	})
+}
+
+// analyzedFingerprints analyzes ast over source and returns the reports
+// with their fingerprints
+func analyzedFingerprints(t *testing.T, ast, source string) ([]Report, []string) {
+	t.Helper()
+	root, err := decodeSolcAST("x.sol", []byte(ast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &GasOptimizer{Path: "x.sol", Source: source, AST: root, Reports: []Report{}}
+	g.Analyze()
+	if len(g.Reports) == 0 {
+		t.Fatal("no reports")
+	}
+	return g.Reports, fingerprints(g.Reports)
+}
+
+func TestFingerprintStableUnderLineShift(t *testing.T) {
+	ast := loopWithIfJSON(dataIJSON)
+	source := strings.Repeat("x", 300)
+	const shift = 40 // blank lines inserted above the contract
+	before, want := analyzedFingerprints(t, ast, source)
+	after, got := analyzedFingerprints(t, shiftSrc(ast, shift), strings.Repeat("\n", shift)+source)
+
+	if len(got) != len(want) {
+		t.Fatalf("%d reports after the shift, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if after[i].line != before[i].line+shift {
+			t.Errorf("%s: line %d after the shift, want %d", before[i].RuleID, after[i].line, before[i].line+shift)
+		}
+		if got[i] != want[i] {
+			t.Errorf("%s: fingerprint changed from %s to %s", before[i].RuleID, want[i], got[i])
+		}
+		if before[i].function != "C.f()" {
+			t.Errorf("%s: function = %q, want C.f()", before[i].RuleID, before[i].function)
+		}
+	}
+}
+
+func TestFingerprintInputs(t *testing.T) {
+	base := Report{RuleID: RuleLoopStorageRead, Issue: "Variable 'data[i]' read 2 times in loop", file: "x.sol", function: "C.f()", line: 3}
+	tests := []struct {
+		name   string
+		change func(Report) Report
+		equal  bool
+	}{
+		{"line", func(r Report) Report { r.line, r.Location = 90, "x.sol:90"; return r }, true},
+		{"issue whitespace", func(r Report) Report { r.Issue = "Variable  'data[i]' read 2 times\tin loop"; return r }, true},
+		{"issue text", func(r Report) Report { r.Issue += " each time"; return r }, false},
+		{"variable", func(r Report) Report { r.Issue = "Variable 'prices[i]' read 2 times in loop"; return r }, false},
+		{"function", func(r Report) Report { r.function = "C.g()"; return r }, false},
+		{"rule", func(r Report) Report { r.RuleID = RuleRepeatedIndexAccess; return r }, false},
+		{"file", func(r Report) Report { r.file = "y.sol"; return r }, false},
+		{"unchanged", func(r Report) Report { return r }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if equal := tt.change(base).Fingerprint() == base.Fingerprint(); equal != tt.equal {
+				t.Errorf("fingerprints equal = %v, want %v", equal, tt.equal)
+			}
+		})
+	}
+
+	// Positions are dropped from the issue, so only their presence counts
+	at := func(span string) Report { r := base; r.Issue += " at " + span; return r }
+	if at("120:15:0").Fingerprint() != at("480:15:0").Fingerprint() {
+		t.Error("fingerprint depends on a span in the issue")
+	}
+	if at("line 4").Fingerprint() != at("line 44").Fingerprint() {
+		t.Error("fingerprint depends on a line in the issue")
+	}
+}
+
+func TestFingerprintsNumberDuplicates(t *testing.T) {
+	r := Report{RuleID: RuleRedundantExpression, Issue: "Expression 'a + b' computed 2 times", file: "x.sol", function: "C.f()"}
+	fps := fingerprints([]Report{r, r, r})
+	if fps[0] != r.Fingerprint() {
+		t.Error("first of identical findings does not keep its Fingerprint")
+	}
+	if fps[0] == fps[1] || fps[1] == fps[2] || fps[0] == fps[2] {
+		t.Errorf("identical findings share fingerprints: %v", fps)
+	}
+}
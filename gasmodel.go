@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GasModel holds the opcode costs savings estimates are built from. The
+// defaults are Ethereum mainnet prices since the Berlin upgrade (EIP-2929
+// warm and cold access); Config.GasCosts overrides them for chains that
+// reprice opcodes, such as L2s.
+type GasModel struct {
+	SloadWarm   int // SLOAD of a slot already accessed in the transaction
+	SloadCold   int // first SLOAD of a slot in the transaction
+	Mload       int
+	Mstore      int
+	SstoreSet   int // SSTORE zero to non-zero
+	SstoreReset int // SSTORE non-zero to non-zero
+	Create      int // CREATE base cost, before code deposit
+	Keccak      int // KECCAK256 base cost
+	KeccakWord  int // KECCAK256 cost per hashed 32-byte word
+	LogDataByte int // LOG cost per byte of non-indexed data
+	Extcodesize int // EXTCODESIZE of an address already accessed in the transaction
+}
+
+// DefaultGasModel is the mainnet gas model
+var DefaultGasModel = GasModel{
+	SloadWarm:   100,
+	SloadCold:   2100,
+	Mload:       3,
+	Mstore:      3,
+	SstoreSet:   20000,
+	SstoreReset: 2900,
+	Create:      32000,
+	Keccak:      30,
+	KeccakWord:  6,
+	LogDataByte: 8,
+	Extcodesize: 100,
+}
+
+// costs maps the gasCosts config keys to the model's fields. SLOAD sets
+// both warm and cold reads, for chains that price them alike.
+func (m *GasModel) costs() map[string][]*int {
+	return map[string][]*int{
+		"SLOAD":          {&m.SloadWarm, &m.SloadCold},
+		"SLOAD_WARM":     {&m.SloadWarm},
+		"SLOAD_COLD":     {&m.SloadCold},
+		"MLOAD":          {&m.Mload},
+		"MSTORE":         {&m.Mstore},
+		"SSTORE_SET":     {&m.SstoreSet},
+		"SSTORE":         {&m.SstoreReset},
+		"CREATE":         {&m.Create},
+		"KECCAK256":      {&m.Keccak},
+		"KECCAK256_WORD": {&m.KeccakWord},
+		"LOG_DATA_BYTE":  {&m.LogDataByte},
+		"EXTCODESIZE":    {&m.Extcodesize},
+	}
+}
+
+// gasCostNames lists the valid gasCosts keys, sorted
+func gasCostNames() string {
+	var names []string
+	for name := range new(GasModel).costs() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// validateGasCosts checks that every key names a cost and no cost is negative
+func validateGasCosts(costs map[string]int) error {
+	known := new(GasModel).costs()
+	for name, cost := range costs {
+		if _, ok := known[name]; !ok {
+			return fmt.Errorf("unknown gas cost %q (want one of %s)", name, gasCostNames())
+		}
+		if cost < 0 {
+			return fmt.Errorf("gas cost %s must not be negative, got %d", name, cost)
+		}
+	}
+	return nil
+}
+
+// GasModel returns DefaultGasModel with the configured gasCosts applied.
+// SLOAD applies first, so SLOAD_WARM or SLOAD_COLD can refine it.
+func (c *Config) GasModel() GasModel {
+	m := DefaultGasModel
+	if c == nil {
+		return m
+	}
+	costs := m.costs()
+	names := make([]string, 0, len(c.GasCosts))
+	for name := range c.GasCosts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] == "SLOAD" && names[j] != "SLOAD" })
+	for _, name := range names {
+		for _, field := range costs[name] {
+			*field = c.GasCosts[name]
+		}
+	}
+	return m
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestGasModelOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		costs      map[string]int
+		warm, cold int
+	}{
+		{"defaults", nil, 100, 2100},
+		{"SLOAD sets both", map[string]int{"SLOAD": 50}, 50, 50},
+		{"SLOAD_COLD refines SLOAD", map[string]int{"SLOAD": 50, "SLOAD_COLD": 400}, 50, 400},
+		{"SLOAD_WARM alone", map[string]int{"SLOAD_WARM": 10}, 10, 2100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := (&Config{GasCosts: tt.costs}).GasModel()
+			if m.SloadWarm != tt.warm || m.SloadCold != tt.cold {
+				t.Errorf("warm, cold = %d, %d; want %d, %d", m.SloadWarm, m.SloadCold, tt.warm, tt.cold)
+			}
+		})
+	}
+}
+
+func TestGasModelAppliesToLoopReads(t *testing.T) {
+	source := "for (uint i = 0; i < n; i++) {\n  total = cfg.limit + cfg.limit;\n}\n"
+	g := &GasOptimizer{Path: "x.sol", Source: source, AST: NewParser(source).Parse(), Reports: []Report{},
+		Config: &Config{GasCosts: map[string]int{"SLOAD": 100}}}
+	g.Analyze()
+	reports := reportsOf(g.Reports, RuleLoopStorageRead)
+	if len(reports) != 1 {
+		t.Fatalf("reports = %+v, want one", reports)
+	}
+	// One read saved per iteration at SLOAD - MLOAD, over 1 to 19 iterations
+	if r := reports[0]; r.GasSavingsMin != 97 || r.GasSavingsMax != 19*97 {
+		t.Errorf("savings range = %d-%d, want 97-%d", r.GasSavingsMin, r.GasSavingsMax, 19*97)
+	}
+}
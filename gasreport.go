@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ForgeGasReport is the output of "forge test --gas-report --json": one
+// entry per tested contract
+type ForgeGasReport []ForgeContractGas
+
+// ForgeContractGas is the measured gas of one contract's functions
+type ForgeContractGas struct {
+	Contract  string                      `json:"contract"` // path:Name
+	Functions map[string]ForgeFunctionGas `json:"functions"`
+}
+
+// ForgeFunctionGas summarizes the calls of one function, keyed by signature
+// such as "transfer(address,uint256)"
+type ForgeFunctionGas struct {
+	Calls  int `json:"calls"`
+	Min    int `json:"min"`
+	Mean   int `json:"mean"`
+	Median int `json:"median"`
+	Max    int `json:"max"`
+}
+
+// ParseForgeGasReport decodes a Foundry JSON gas report
+func ParseForgeGasReport(r io.Reader) (ForgeGasReport, error) {
+	var report ForgeGasReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return nil, fmt.Errorf("parsing forge gas report: %v", err)
+	}
+	return report, nil
+}
+
+// LoadForgeGasReport reads a Foundry JSON gas report from path
+func LoadForgeGasReport(path string) (ForgeGasReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseForgeGasReport(f)
+}
+
+// Correlation statuses of a function
+const (
+	GasMatched    = "matched"    // has findings and was measured
+	GasUnmeasured = "unmeasured" // has findings but no measured calls
+	GasMissed     = "missed"     // measured as expensive, but no findings
+)
+
+// FunctionGas sets a function's estimated savings against its measured gas
+type FunctionGas struct {
+	Contract         string `json:"contract"`
+	Function         string `json:"function"`
+	EstimatedSavings int    `json:"estimatedSavings"`
+	Calls            int    `json:"calls"`
+	MeanGas          int    `json:"meanGas"`
+	Status           string `json:"status"`
+}
+
+// CorrelateGasReport matches the per-function savings of the analysis with
+// measured gas. Functions with findings are matched or unmeasured; the
+// latter were never called by the tests, or are internal, and their
+// findings deserve a second look. Measured functions without findings are
+// reported as missed when their mean gas is above the average of all
+// measured functions. Functions are matched by contract name and
+// signature; results are sorted by contract, then function.
+func (g *GasOptimizer) CorrelateGasReport(report ForgeGasReport) []FunctionGas {
+	measured := make(map[string]ForgeFunctionGas)
+	total, count := 0, 0
+	for _, c := range report {
+		name := c.Contract[strings.LastIndex(c.Contract, ":")+1:]
+		for sig, fn := range c.Functions {
+			measured[name+":"+sig] = fn
+			if fn.Calls > 0 {
+				total += fn.Mean
+				count++
+			}
+		}
+	}
+	average := 0
+	if count > 0 {
+		average = total / count
+	}
+	var out []FunctionGas
+	for _, m := range g.Metrics {
+		for _, f := range m.functions {
+			fn, ok := measured[m.Contract+":"+f.Signature]
+			row := FunctionGas{Contract: m.Contract, Function: f.Signature, EstimatedSavings: f.Savings, Calls: fn.Calls, MeanGas: fn.Mean}
+			switch {
+			case f.Savings > 0 && ok && fn.Calls > 0:
+				row.Status = GasMatched
+			case f.Savings > 0:
+				row.Status = GasUnmeasured
+			case ok && fn.Calls > 0 && fn.Mean > average:
+				row.Status = GasMissed
+			default:
+				continue
+			}
+			out = append(out, row)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Contract != out[j].Contract {
+			return out[i].Contract < out[j].Contract
+		}
+		return out[i].Function < out[j].Function
+	})
+	return out
+}
+
+// PrintGasCorrelation displays the correlation with a gas report
+func (g *GasOptimizer) PrintGasCorrelation() {
+	if len(g.GasReport) == 0 {
+		fmt.Println("No functions to correlate with the gas report (requires solc).")
+		return
+	}
+	fmt.Println("Gas report correlation:")
+	for _, f := range g.GasReport {
+		switch f.Status {
+		case GasMatched:
+			fmt.Printf("  %s:%s: ~%d gas saved of %d measured (%d calls)\n", f.Contract, f.Function, f.EstimatedSavings, f.MeanGas, f.Calls)
+		case GasUnmeasured:
+			fmt.Printf("  %s:%s: ~%d gas estimated, but never measured; check the findings or add a test\n", f.Contract, f.Function, f.EstimatedSavings)
+		case GasMissed:
+			fmt.Printf("  %s:%s: %d gas measured (%d calls), above average, with no findings\n", f.Contract, f.Function, f.MeanGas, f.Calls)
+		}
+	}
+	fmt.Println()
+}
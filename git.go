@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// lineRange is an inclusive range of 1-based line numbers
+type lineRange struct {
+	Start, End int
+}
+
+// hunkHeader matches the new-file side of a unified diff hunk, "+start[,count]"
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// changedLines returns the lines of file added or modified since ref, per
+// git diff. A file git does not track counts as entirely changed.
+func changedLines(file, ref string) ([]lineRange, error) {
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	if err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Run(); err != nil {
+		return nil, fmt.Errorf("git: cannot resolve %q in %s: %v", ref, dir, err)
+	}
+	if err := exec.Command("git", "-C", dir, "ls-files", "--error-unmatch", base).Run(); err != nil {
+		return []lineRange{{1, math.MaxInt}}, nil
+	}
+	out, err := exec.Command("git", "-C", dir, "diff", "-U0", "--no-color", ref, "--", base).Output()
+	if err != nil {
+		return nil, fmt.Errorf("git diff %s -- %s: %v", ref, file, err)
+	}
+	return parseHunks(out), nil
+}
+
+// parseHunks extracts added line ranges from unified diff output
+func parseHunks(diff []byte) []lineRange {
+	var ranges []lineRange
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	for scanner.Scan() {
+		m := hunkHeader.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		start, _ := strconv.Atoi(m[1])
+		count := 1
+		if m[2] != "" {
+			count, _ = strconv.Atoi(m[2])
+		}
+		if count == 0 {
+			continue // pure deletion
+		}
+		ranges = append(ranges, lineRange{start, start + count - 1})
+	}
+	return ranges
+}
+
+// FilterChangedLines keeps only reports whose span overlaps a changed range,
+// and rescores the metrics from them
+func (g *GasOptimizer) FilterChangedLines(ranges []lineRange) {
+	kept := g.Reports[:0]
+	for _, r := range g.Reports {
+		for _, lr := range ranges {
+			if r.startLine <= lr.End && r.endLine >= lr.Start {
+				kept = append(kept, r)
+				break
+			}
+		}
+	}
+	g.Reports = kept
+	g.scoreMetrics()
+}
+
+// gitWorktree checks out ref into a new temporary worktree of the git
+// repository containing path, leaving the working tree alone. It returns
+// the worktree, the repository root and a function removing the worktree.
+func gitWorktree(path, ref string) (worktree, root string, cleanup func(), err error) {
+	if root, err = gitRoot(path); err != nil {
+		return "", "", nil, err
+	}
+	if ref, err = resolveCommit(root, ref); err != nil {
+		return "", "", nil, err
+	}
+	worktree, err = os.MkdirTemp("", "gasoptimizer-worktree-")
+	if err != nil {
+		return "", "", nil, err
+	}
+	if out, err := exec.Command("git", "-C", root, "worktree", "add", "--detach", worktree, ref).CombinedOutput(); err != nil {
+		os.RemoveAll(worktree)
+		return "", "", nil, fmt.Errorf("git worktree add %s: %v: %s", ref, err, bytes.TrimSpace(out))
+	}
+	cleanup = func() {
+		exec.Command("git", "-C", root, "worktree", "remove", "--force", worktree).Run()
+		os.RemoveAll(worktree)
+	}
+	return worktree, root, cleanup, nil
+}
+
+// gitRoot returns the root of the git repository containing path
+func gitRoot(path string) (string, error) {
+	dir := path
+	if !isDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not in a git repository", path)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// mergeBase returns the commit where HEAD branched off ref in the
+// repository at dir
+func mergeBase(dir, ref string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "merge-base", ref, "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git merge-base %s HEAD: %v", ref, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// resolveCommit returns the commit hash ref names in the repository at dir.
+// Refs such as HEAD are per worktree, so refs meant for the user's checkout
+// are resolved there before use in another worktree.
+func resolveCommit(dir, ref string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("git: cannot resolve %q in %s: %v", ref, dir, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// worktreePaths maps paths in the repository at root to the same paths in
+// worktree
+func worktreePaths(paths []string, root, worktree string) ([]string, error) {
+	// Resolve symlinks so paths compare with what git reports
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+	mapped := make([]string, len(paths))
+	for i, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		if real, err := filepath.EvalSymlinks(abs); err == nil {
+			abs = real // a path missing from the working tree may exist at the ref
+		}
+		rel, err := filepath.Rel(realRoot, abs)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s is outside the repository at %s", path, root)
+		}
+		mapped[i] = filepath.Join(worktree, rel)
+	}
+	return mapped, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSourceBytes caps the size of a POST /analyze request body
+const DefaultMaxSourceBytes = 1 << 20
+
+// analyzeRequest is the body of POST /analyze
+type analyzeRequest struct {
+	Source string `json:"source"`
+}
+
+// httpError is the body of an error response
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// httpServer answers POST /analyze with the analysis of the posted source,
+// running at most cap(slots) analyses at a time
+type httpServer struct {
+	cfg      *Config
+	opts     Options
+	slots    chan struct{}
+	maxBytes int64
+}
+
+// runHTTP implements "gasoptimizer http [flags]"
+func runHTTP(args []string) int {
+	fs := flag.NewFlagSet("http", flag.ContinueOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	configPath := fs.String("config", "", "path to JSON config (default "+DefaultConfigFile+" if present)")
+	cacheDir := fs.String("cache-dir", DefaultCacheDir(), "directory caching solc ASTs by source hash")
+	concurrency := fs.Int("concurrency", runtime.NumCPU(), "analyses run at once; further requests wait for a free slot")
+	maxBytes := fs.Int64("max-bytes", DefaultMaxSourceBytes, "largest accepted request body in bytes")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gasoptimizer http [flags]")
+		fmt.Fprintln(fs.Output(), "Serves POST /analyze with {\"source\": \"...\"} and GET /healthz.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+	if *concurrency < 1 || *maxBytes < 1 {
+		log.Printf("Error: --concurrency and --max-bytes must be positive")
+		return ExitUsage
+	}
+	cfg, err := loadConfigFlag(*configPath)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	s := &httpServer{
+		cfg:      cfg,
+		opts:     Options{CacheDir: *cacheDir, Logger: DiscardLogger},
+		slots:    make(chan struct{}, *concurrency),
+		maxBytes: *maxBytes,
+	}
+	srv := &http.Server{Addr: *addr, Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+	log.Printf("Listening on %s", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// handler routes the endpoints
+func (s *httpServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /analyze", s.analyze)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// analyze answers with the reports as in --format json. The source never
+// touches disk unless solc is installed, which reads it from a temporary
+// file removed before the response is written.
+func (s *httpServer) analyze(w http.ResponseWriter, r *http.Request) {
+	var req analyzeRequest
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBytes))
+	if err := dec.Decode(&req); err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeHTTPError(w, status, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if strings.TrimSpace(req.Source) == "" {
+		writeHTTPError(w, http.StatusBadRequest, `missing "source"`)
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		return // the client gave up while waiting
+	}
+	g, err := NewGasOptimizerFromReaderOptions(r.Context(), strings.NewReader(req.Source), s.opts)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	g.Config = s.cfg
+	g.Analyze()
+	sortModes[DefaultSort](g.Reports)
+	var buf bytes.Buffer
+	if err := g.WriteJSON(&buf); err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(buf.Bytes())
+}
+
+// writeHTTPError answers with status and a JSON error message
+func writeHTTPError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(httpError{Error: message})
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testHTTPServer serves the handler with the custom parser, so results do
+// not depend on solc being installed
+func testHTTPServer(t *testing.T, maxBytes int64) *httptest.Server {
+	t.Helper()
+	s := &httpServer{
+		opts:     Options{NoSolc: true, Logger: DiscardLogger},
+		slots:    make(chan struct{}, 2),
+		maxBytes: maxBytes,
+	}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPHealthz(t *testing.T) {
+	srv := testHTTPServer(t, DefaultMaxSourceBytes)
+	resp, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestHTTPAnalyze(t *testing.T) {
+	srv := testHTTPServer(t, DefaultMaxSourceBytes)
+	source := "for (uint i = 0; i < n; i++) {\n  total = cfg.limit + cfg.limit;\n}\n"
+	body, _ := json.Marshal(analyzeRequest{Source: source})
+	resp, err := http.Post(srv.URL+"/analyze", "application/json", strings.NewReader(string(body)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var out struct {
+		File    string       `json:"file"`
+		Reports []jsonReport `json:"reports"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.File != readerPath {
+		t.Errorf("file = %q, want %q", out.File, readerPath)
+	}
+	if len(out.Reports) != 1 || out.Reports[0].RuleID != RuleLoopStorageRead || out.Reports[0].Fingerprint == "" {
+		t.Errorf("reports = %+v, want one fingerprinted %s", out.Reports, RuleLoopStorageRead)
+	}
+}
+
+func TestHTTPAnalyzeErrors(t *testing.T) {
+	srv := testHTTPServer(t, 64)
+	tests := []struct {
+		name, method, body string
+		status             int
+	}{
+		{"invalid JSON", http.MethodPost, `{"source":`, http.StatusBadRequest},
+		{"missing source", http.MethodPost, `{}`, http.StatusBadRequest},
+		{"blank source", http.MethodPost, `{"source":"  \n"}`, http.StatusBadRequest},
+		{"too large", http.MethodPost, `{"source":"` + strings.Repeat("x", 100) + `"}`, http.StatusRequestEntityTooLarge},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, srv.URL+"/analyze", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.method != http.MethodPost {
+				return
+			}
+			var e httpError
+			if err := json.NewDecoder(resp.Body).Decode(&e); err != nil || e.Error == "" {
+				t.Errorf("body is not a JSON error: %v %+v", err, e)
+			}
+		})
+	}
+}
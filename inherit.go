@@ -0,0 +1,40 @@
+package main
+
+// sourceUnits returns the analyzed source unit followed by the units it
+// imports. Definitions such as state variables, structs and base
+// contracts are collected from all of them; findings only from the first.
+func (g *GasOptimizer) sourceUnits(ast *SolcASTNode) []*SolcASTNode {
+	return append([]*SolcASTNode{ast}, ast.imports...)
+}
+
+// contractIndex maps the IDs of the contracts defined in the analyzed
+// source and its imports to their definitions
+func (g *GasOptimizer) contractIndex(ast *SolcASTNode) map[int]*SolcASTNode {
+	index := make(map[int]*SolcASTNode)
+	for _, unit := range g.sourceUnits(ast) {
+		g.walkSolcAST(unit, func(node *SolcASTNode) {
+			if node.NodeType == "ContractDefinition" {
+				index[node.ID] = node
+			}
+		})
+	}
+	return index
+}
+
+// linearized returns contract and its base contracts, most derived first.
+// Bases whose definitions are not in the index, such as those of an AST
+// without imports, are left out.
+func linearized(contract *SolcASTNode, index map[int]*SolcASTNode) []*SolcASTNode {
+	if len(contract.LinearizedBaseContracts) == 0 {
+		return []*SolcASTNode{contract}
+	}
+	var chain []*SolcASTNode
+	for _, id := range contract.LinearizedBaseContracts {
+		if id == contract.ID {
+			chain = append(chain, contract)
+		} else if base := index[id]; base != nil {
+			chain = append(chain, base)
+		}
+	}
+	return chain
+}
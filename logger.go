@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+)
+
+// DiscardLogger drops every message; set it as Options.Logger to keep the
+// optimizer silent when embedding it
+var DiscardLogger = slog.New(discardHandler{})
+
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// logger returns the configured logger, defaulting to slog.Default, which
+// writes to stderr through the standard log package
+func (o Options) logger() *slog.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return slog.Default()
+}
+
+// logger returns the optimizer's logger, defaulting to slog.Default
+func (g *GasOptimizer) logger() *slog.Logger {
+	if g.Logger != nil {
+		return g.Logger
+	}
+	return slog.Default()
+}
@@ -1,26 +1,85 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 )
 
-// Gas costs (approximate, post-EIP-2929)
+// Approximate costs of compound operations; opcode costs are in GasModel
 const (
-	GasSload = 800 // SLOAD cost
-	GasMload = 3   // MLOAD cost
+	GasRevertStringWord = 50  // encoding and storing one 32-byte word of revert string
+	GasConditionCheck   = 20  // evaluating a condition and JUMPI
+	GasMemoryAlloc      = 60  // bumping the free memory pointer and zeroing a small allocation
+	GasStructCopy       = 200 // copying a small struct into memory at a call site
+	GasIndexAccess      = 30  // bounds check and offset computation of a memory index access
+
+	GasLoopIteration    = 50   // per-iteration counter increment, bound check and jump
+	GasSafeMathCall     = 100  // internal jump and duplicated overflow check of a SafeMath call
+	GasSelfCall         = 500  // CALL to this, ABI encoding/decoding and the callee's dispatch
+	GasInlinedStatement = 4000 // deployment cost of one statement's bytecode (~20 bytes at 200 gas/byte)
+	GasMutability       = 24   // non-payable callvalue check and state-access overhead of an unmarked function
+	GasExternalCall     = 700  // warm CALL, ABI encoding/decoding and a typical view function body
+	GasCheckedIncrement = 30   // overflow check of a checked ++i
+	GasConversion       = 15   // stack and pointer shuffling of a bytes/string conversion
+	GasParamDecode      = 20   // decoding, validating and stack handling of one parameter
+	GasCheckedOp        = 30   // checked arithmetic operation, including its overflow check
+	GasSignedCheck      = 10   // extra sign handling in the overflow checks of signed arithmetic
 )
 
 // Report represents an optimization suggestion
 type Report struct {
-	Issue      string
-	Suggestion string
-	GasSavings int
-	Location   string
+	RuleID     string     `json:"ruleId"`
+	Category   Category   `json:"category"`
+	Area       Area       `json:"area"`
+	Severity   Severity   `json:"severity"`
+	Confidence Confidence `json:"confidence"`
+	Effort     Effort     `json:"effort"`
+	Level      Level      `json:"level"`
+	Issue      string     `json:"issue"`
+	Suggestion string     `json:"suggestion"`
+	GasSavings int        `json:"gasSavings"` // midpoint of GasSavingsMin and GasSavingsMax
+	Location   string     `json:"location"`
+	Src        string     `json:"src,omitempty"` // raw solc span, kept after Location is resolved
+
+	// SourceSnippet is the source text of the span, plus Context lines
+	// around it, so the report stands alone without the file
+	SourceSnippet string `json:"sourceSnippet,omitempty"`
+
+	// GasSavingsMin and GasSavingsMax bound estimates that depend on the
+	// iteration count or on slots being cold or warm; both equal GasSavings
+	// for fixed estimates
+	GasSavingsMin int `json:"gasSavingsMin"`
+	GasSavingsMax int `json:"gasSavingsMax"`
+
+	// Range is the full span of a solc finding in the original file, for
+	// outputs that highlight the whole expression; nil for line-only findings
+	Range *Range `json:"range,omitempty"`
+
+	startLine, endLine int    // span in the analyzed file, before flattening is undone
+	file               string // original file and line Location points at
+	line               int
+	function           string // enclosing Contract.name(types), or Contract; "" if unknown
+}
+
+// Range is a span as 1-based lines and columns; the end column is
+// exclusive, pointing just past the last character
+type Range struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
 }
 
 // SolcASTNode represents a node in the solc-generated AST
@@ -45,6 +104,73 @@ type SolcASTNode struct {
 	ReferencedDecl   int           `json:"referencedDeclaration,omitempty"`
 	Operator         string        `json:"operator,omitempty"`
 	Value            string        `json:"value,omitempty"`
+	Kind             string        `json:"kind,omitempty"`
+	Condition        *SolcASTNode  `json:"condition,omitempty"`
+	TrueBody         *SolcASTNode  `json:"trueBody,omitempty"`
+	FalseBody        *SolcASTNode  `json:"falseBody,omitempty"`
+	Arguments        []SolcASTNode `json:"arguments,omitempty"`
+	ID               int           `json:"id,omitempty"`
+	Declarations     []SolcASTNode `json:"declarations,omitempty"`
+	StorageLocation  string        `json:"storageLocation,omitempty"`
+	LeftHandSide     *SolcASTNode  `json:"leftHandSide,omitempty"`
+	RightHandSide    *SolcASTNode  `json:"rightHandSide,omitempty"`
+	SubExpression    *SolcASTNode  `json:"subExpression,omitempty"`
+	Visibility       string        `json:"visibility,omitempty"`
+	MemberName       string        `json:"memberName,omitempty"`
+	Constant         bool          `json:"constant,omitempty"`
+	Mutability       string        `json:"mutability,omitempty"`
+	StateMutability  string        `json:"stateMutability,omitempty"`
+	Virtual          bool          `json:"virtual,omitempty"`
+	Modifiers        []SolcASTNode `json:"modifiers,omitempty"`
+	ModifierName     *SolcASTNode  `json:"modifierName,omitempty"`
+	Literals         []string      `json:"literals,omitempty"`
+	Indexed          bool          `json:"indexed,omitempty"`
+	Names            []string      `json:"names,omitempty"`     // argument names of a call with {name: value} syntax
+	Options          []SolcASTNode `json:"options,omitempty"`   // values of a FunctionCallOptions, named by Names
+	Members          []SolcASTNode `json:"members,omitempty"`   // fields of a StructDefinition
+	Overrides        *SolcASTNode  `json:"overrides,omitempty"` // override specifier of a function
+	BaseFunctions    []int         `json:"baseFunctions,omitempty"`
+	AbsolutePath     string        `json:"absolutePath,omitempty"` // source path of a SourceUnit
+	ContractKind     string        `json:"contractKind,omitempty"` // contract, interface or library
+
+	// LinearizedBaseContracts lists a contract's ID and its bases' IDs,
+	// most derived first, in C3 linearization order
+	LinearizedBaseContracts []int `json:"linearizedBaseContracts,omitempty"`
+
+	InitializationExpression *SolcASTNode `json:"initializationExpression,omitempty"`
+	LoopExpression           *SolcASTNode `json:"loopExpression,omitempty"`
+
+	// Components of a TupleExpression; a parenthesized expression is a
+	// one-element tuple. Omitted elements, as in (, b) = f(), are nil.
+	Components    []*SolcASTNode `json:"components,omitempty"`
+	IsInlineArray bool           `json:"isInlineArray,omitempty"` // [a, b, c] rather than (a, b)
+
+	// try/catch: a TryStatement's externalCall and clauses, each clause
+	// (TryCatchClause) holding its block
+	ExternalCall *SolcASTNode  `json:"externalCall,omitempty"`
+	Clauses      []SolcASTNode `json:"clauses,omitempty"`
+	Block        *SolcASTNode  `json:"block,omitempty"`
+
+	// EventCall of an EmitStatement; not descended by the walkers, though
+	// referencedDecls looks into it
+	EventCall *SolcASTNode `json:"eventCall,omitempty"`
+
+	// Assembly is the Yul AST of an InlineAssembly statement, and
+	// ExternalReferences the Solidity declarations its identifiers name
+	Assembly           *YulNode            `json:"AST,omitempty"`
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+
+	// imports are the other source units solc compiled along with a root
+	// SourceUnit, holding the definitions of imported base contracts. They
+	// are consulted for definitions but never walked for findings.
+	imports []*SolcASTNode
+}
+
+// ExternalReference resolves an identifier in inline assembly, such as x
+// or p.slot, to its Solidity declaration
+type ExternalReference struct {
+	Declaration int    `json:"declaration"`
+	Suffix      string `json:"suffix,omitempty"` // slot, offset, length, selector or address
 }
 
 type TypeDesc struct {
@@ -58,56 +184,374 @@ type ParamList struct {
 
 // GasOptimizer holds the state of the analysis
 type GasOptimizer struct {
+	Path    string
 	Source  string
 	AST     interface{}
+	Config  *Config
+	Context int          // lines of source around each report's SourceSnippet
+	Logger  *slog.Logger // diagnostics such as the solc fallback; nil means slog.Default
+	SolcErr error        // why solc was not used (ErrSolcNotFound/ErrSolcFailed/ErrSolcSkipped), nil if it was
 	Reports []Report
+	Metrics []ContractMetrics
+
+	// GasReport correlates per-function savings with a Foundry gas report,
+	// when one was given
+	GasReport []FunctionGas
+
+	MaxReports int // findings kept per analysis; 0 means no limit
+	Suppressed int // findings dropped once MaxReports was reached
+
+	gas GasModel // opcode costs from Config, set by Analyze
 }
 
-// NewGasOptimizer creates a new optimizer instance
+// Options tune how sources are loaded and reported
+type Options struct {
+	CacheDir string // where solc ASTs are cached by content hash; "" disables caching
+	Context  int    // lines of source around each report's SourceSnippet
+
+	// Logger receives diagnostics such as the solc fallback notice; nil
+	// means slog.Default. Use DiscardLogger to silence them.
+	Logger *slog.Logger
+
+	MaxReports int // findings kept per file; 0 means no limit
+
+	// NoSolc parses Solidity with the custom parser without running solc
+	// or reading the AST cache. Yul files cannot be analyzed then.
+	NoSolc bool
+}
+
+// NewGasOptimizer creates a new optimizer instance. When solc is missing or
+// fails, it falls back to the custom parser and records why in SolcErr.
 func NewGasOptimizer(filePath string) (*GasOptimizer, error) {
+	return NewGasOptimizerContext(context.Background(), filePath)
+}
+
+// NewGasOptimizerContext is NewGasOptimizer with a context; cancelling it
+// kills a running solc and returns the context's error
+func NewGasOptimizerContext(ctx context.Context, filePath string) (*GasOptimizer, error) {
+	return NewGasOptimizerOptions(ctx, filePath, Options{})
+}
+
+// NewGasOptimizerOptions is NewGasOptimizerContext with options
+func NewGasOptimizerOptions(ctx context.Context, filePath string, opts Options) (*GasOptimizer, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %v", err)
+		return nil, &AnalysisError{Kind: ErrReadFile, Path: filePath, Err: err}
 	}
 	source := string(data)
 
-	cmd := exec.Command("solc", "--ast-compact-json", filePath)
+	if isYulFile(filePath) {
+		if opts.NoSolc {
+			return nil, &AnalysisError{Kind: ErrSolcSkipped, Path: filePath, Err: errors.New("Yul files require solc")}
+		}
+		ast, err := parseYul(ctx, filePath)
+		if err != nil {
+			return nil, err
+		}
+		return &GasOptimizer{Path: filePath, Source: source, AST: ast, Context: opts.Context, Logger: opts.Logger, Reports: []Report{}}, nil
+	}
+
+	if opts.NoSolc {
+		return customOptimizer(filePath, source, &AnalysisError{Kind: ErrSolcSkipped, Path: filePath}, opts), nil
+	}
+	cache := astCache{dir: opts.CacheDir}
+	if cache.dir != "" {
+		if cache.compiler, err = compilerID(ctx); err != nil {
+			cache.dir = "" // solc is missing or broken, so the run below falls back
+		}
+	}
+	if cached, ok := cache.get(data); ok {
+		if ast, err := decodeSolcAST(filePath, cached); err == nil {
+			opts.logger().Debug("using cached AST", "path", filePath)
+			return &GasOptimizer{Path: filePath, Source: source, AST: ast, Context: opts.Context, Logger: opts.Logger, Reports: []Report{}}, nil
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "solc", "--ast-compact-json", filePath)
 	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err != nil {
-		log.Printf("solc failed: %v, falling back to custom parser", err)
-		parser := NewParser(source)
-		ast := parser.Parse()
-		return &GasOptimizer{Source: source, AST: ast, Reports: []Report{}}, nil
+		solcErr := &AnalysisError{Kind: ErrSolcFailed, Path: filePath, Err: err, Detail: string(output)}
+		if errors.Is(err, exec.ErrNotFound) {
+			solcErr = &AnalysisError{Kind: ErrSolcNotFound, Path: filePath, Err: err}
+		}
+		return fallbackOptimizer(filePath, source, solcErr, opts), nil
 	}
 
-	re := regexp.MustCompile(`(?s)JSON AST \(compact format\):.*?({.*})`)
-	matches := re.FindSubmatch(output)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("no JSON found in solc output: %s", string(output))
+	jsonData, err := extractSolcJSON(filePath, output)
+	if err != nil {
+		return nil, err
 	}
-	jsonData := matches[1]
-
-	var ast interface{}
-	if err := json.Unmarshal(jsonData, &ast); err != nil {
-		return nil, fmt.Errorf("failed to parse AST: %v, output: %s", err, string(jsonData))
+	ast, err := decodeSolcAST(filePath, jsonData)
+	if err != nil {
+		return nil, err
+	}
+	if len(ast.imports) == 0 {
+		// The AST of a file with imports also depends on the imported
+		// files, which the cache key does not cover
+		cache.put(data, jsonData)
 	}
 
 	return &GasOptimizer{
+		Path:    filePath,
 		Source:  source,
 		AST:     ast,
+		Context: opts.Context,
+		Logger:  opts.Logger,
 		Reports: []Report{},
 	}, nil
 }
 
+// fallbackOptimizer parses source with the custom parser after solc failed
+// with solcErr
+func fallbackOptimizer(filePath, source string, solcErr *AnalysisError, opts Options) *GasOptimizer {
+	opts.logger().Warn("solc failed, falling back to custom parser", "path", filePath, "err", solcErr.Err)
+	return customOptimizer(filePath, source, solcErr, opts)
+}
+
+// customOptimizer parses source with the custom parser, recording in
+// solcErr why solc was not used
+func customOptimizer(filePath, source string, solcErr *AnalysisError, opts Options) *GasOptimizer {
+	parser := NewParser(source)
+	ast := parser.Parse()
+	return &GasOptimizer{Path: filePath, Source: source, AST: ast, Context: opts.Context, Logger: opts.Logger, SolcErr: solcErr, Reports: []Report{}}
+}
+
+// readerPath is the Path of optimizers created from a reader
+const readerPath = "<input>"
+
+// NewGasOptimizerFromReader creates an optimizer for Solidity source read
+// from r, such as an editor buffer
+func NewGasOptimizerFromReader(r io.Reader) (*GasOptimizer, error) {
+	return NewGasOptimizerFromReaderOptions(context.Background(), r, Options{})
+}
+
+// NewGasOptimizerFromReaderOptions is NewGasOptimizerFromReader with a
+// context and options. solc only reads files, so when it is installed the
+// source is streamed to a temporary file; otherwise it goes straight to the
+// custom parser, as it does with opts.NoSolc.
+func NewGasOptimizerFromReaderOptions(ctx context.Context, r io.Reader, opts Options) (*GasOptimizer, error) {
+	if opts.NoSolc {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, &AnalysisError{Kind: ErrReadFile, Path: readerPath, Err: err}
+		}
+		return customOptimizer(readerPath, string(data), &AnalysisError{Kind: ErrSolcSkipped, Path: readerPath}, opts), nil
+	}
+	if _, lookErr := exec.LookPath("solc"); lookErr != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, &AnalysisError{Kind: ErrReadFile, Path: readerPath, Err: err}
+		}
+		solcErr := &AnalysisError{Kind: ErrSolcNotFound, Path: readerPath, Err: lookErr}
+		return fallbackOptimizer(readerPath, string(data), solcErr, opts), nil
+	}
+
+	f, err := os.CreateTemp("", "gasoptimizer-*.sol")
+	if err != nil {
+		return nil, &AnalysisError{Kind: ErrReadFile, Path: readerPath, Err: err}
+	}
+	defer os.Remove(f.Name())
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, &AnalysisError{Kind: ErrReadFile, Path: readerPath, Err: err}
+	}
+	g, err := NewGasOptimizerOptions(ctx, f.Name(), opts)
+	if err != nil {
+		return nil, err
+	}
+	g.Path = readerPath
+	return g, nil
+}
+
+// solcSourceHeader precedes each source file's AST in solc output
+var solcSourceHeader = regexp.MustCompile(`(?m)^======= .* =======\s*$`)
+
+// extractSolcJSON finds the compact JSON AST in solc --ast-compact-json
+// output. When the file imports others, solc prints one AST per source
+// file, each after a header; they are returned together as a JSON array.
+func extractSolcJSON(filePath string, output []byte) ([]byte, error) {
+	start := bytes.Index(output, []byte("JSON AST (compact format):"))
+	if start < 0 || bytes.IndexByte(output[start:], '{') < 0 {
+		return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: errors.New("no JSON found in solc output"), Detail: string(output)}
+	}
+	output = output[start:]
+	headers := solcSourceHeader.FindAllIndex(output, -1)
+	if len(headers) == 0 {
+		headers = [][]int{{0, len("JSON AST (compact format):")}}
+	}
+	var units []json.RawMessage
+	for _, h := range headers {
+		rest := bytes.TrimLeft(output[h[1]:], " \t\r\n")
+		if len(rest) == 0 || rest[0] != '{' {
+			continue
+		}
+		var unit json.RawMessage
+		if err := json.NewDecoder(bytes.NewReader(rest)).Decode(&unit); err != nil {
+			return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: err, Detail: string(output)}
+		}
+		units = append(units, unit)
+	}
+	switch len(units) {
+	case 0:
+		return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: errors.New("no JSON found in solc output"), Detail: string(output)}
+	case 1:
+		return units[0], nil
+	}
+	return json.Marshal(units)
+}
+
+// parseSolcOutput extracts and decodes the compact JSON AST from solc output
+func parseSolcOutput(filePath string, output []byte) (*SolcASTNode, error) {
+	jsonData, err := extractSolcJSON(filePath, output)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSolcAST(filePath, jsonData)
+}
+
+// decodeSolcAST decodes solc's compact JSON AST straight into SolcASTNode.
+// For an array of source units, the unit of filePath is returned with the
+// others as its imports.
+func decodeSolcAST(filePath string, jsonData []byte) (*SolcASTNode, error) {
+	if trimmed := bytes.TrimSpace(jsonData); len(trimmed) > 0 && trimmed[0] == '[' {
+		var units []*SolcASTNode
+		if err := json.Unmarshal(trimmed, &units); err != nil {
+			return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: err, Detail: string(jsonData)}
+		}
+		if len(units) == 0 {
+			return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: errors.New("no source units")}
+		}
+		root := 0
+		for i, unit := range units {
+			if filepath.Clean(unit.AbsolutePath) == filepath.Clean(filePath) {
+				root = i
+				break
+			}
+		}
+		ast := units[root]
+		ast.imports = append(units[:root:root], units[root+1:]...)
+		return ast, nil
+	}
+	var root SolcASTNode
+	if err := json.Unmarshal(jsonData, &root); err != nil {
+		return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: err, Detail: string(jsonData)}
+	}
+	return &root, nil
+}
+
 // Analyze runs the gas optimization analysis
 func (g *GasOptimizer) Analyze() {
+	g.gas = g.Config.GasModel()
 	switch ast := g.AST.(type) {
 	case *Node:
-		g.analyzeCustomAST(ast)
-	case interface{}:
+		if g.Config.runs(RuleLoopStorageRead) {
+			g.analyzeCustomAST(ast)
+		}
+	case *SolcASTNode:
 		g.analyzeSolcAST(ast)
+	case *YulNode:
+		if g.Config.runs(RuleYulRepeatedSload, RuleYulRedundantMstore) {
+			g.analyzeYulAST(ast)
+		}
+	case interface{}:
+		// A generic JSON value, e.g. solc output decoded by a library caller
+		astBytes, _ := json.Marshal(ast)
+		var root SolcASTNode
+		json.Unmarshal(astBytes, &root)
+		g.analyzeSolcAST(&root)
 	default:
-		log.Println("Unknown AST type, skipping analysis")
+		g.logger().Error("unknown AST type, skipping analysis", "path", g.Path)
+	}
+	g.applyRules()
+	g.scoreMetrics()
+	g.resolveLocations()
+}
+
+// addReport records a detector finding. Findings that applyRules would
+// drop are dropped here already so they do not count towards MaxReports;
+// past the limit, findings are only counted in Suppressed.
+func (g *GasOptimizer) addReport(r Report) {
+	if g.Config.LevelFor(r.RuleID) == LevelOff {
+		return
+	}
+	if rule, ok := findRule(r.RuleID); ok && !g.Config.meetsConfidence(rule.Confidence) {
+		return
+	}
+	if g.MaxReports > 0 && len(g.Reports) >= g.MaxReports {
+		g.Suppressed++
+		return
+	}
+	g.Reports = append(g.Reports, r)
+}
+
+// applyRules tags reports with their rule's category, area, severity, confidence and
+// configured level, and drops reports from rules turned off or below the
+// configured minimum confidence
+func (g *GasOptimizer) applyRules() {
+	kept := g.Reports[:0]
+	for _, r := range g.Reports {
+		if rule, ok := findRule(r.RuleID); ok {
+			r.Category = rule.Category
+			r.Area = rule.Area
+			r.Severity = rule.Severity
+			r.Confidence = rule.Confidence
+			r.Effort = rule.Effort
+		}
+		r.Level = g.Config.LevelFor(r.RuleID)
+		if r.GasSavingsMin == 0 && r.GasSavingsMax == 0 {
+			r.GasSavingsMin, r.GasSavingsMax = r.GasSavings, r.GasSavings
+		}
+		if r.Level != LevelOff && g.Config.meetsConfidence(r.Confidence) {
+			kept = append(kept, r)
+		}
+	}
+	g.Reports = kept
+}
+
+// HasErrors reports whether any error-level findings remain
+func (g *GasOptimizer) HasErrors() bool {
+	for _, r := range g.Reports {
+		if r.Level == LevelError {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveLocations rewrites report locations as file:line, following
+// flattening markers back to the original file, and fills in the Range of
+// solc spans
+func (g *GasOptimizer) resolveLocations() {
+	sm := newSourceMap(g.Path, g.Source)
+	for i := range g.Reports {
+		r := &g.Reports[i]
+		var span *Range
+		if start, length, ok := parseSrc(r.Location); ok {
+			span = &Range{}
+			r.Src = r.Location
+			r.startLine, span.StartColumn = sm.Position(start)
+			r.endLine, span.EndColumn = sm.Position(start + length)
+			r.SourceSnippet = sm.Snippet(start, start+length, g.Context)
+		} else if n, err := strconv.Atoi(strings.TrimPrefix(r.Location, "line ")); err == nil {
+			r.startLine, r.endLine = n, n
+			r.SourceSnippet = sm.LineSnippet(n, n, g.Context)
+		}
+		r.file, r.line = g.Path, r.startLine
+		if r.startLine > 0 {
+			r.file, r.line = sm.Original(r.startLine)
+		}
+		if span != nil {
+			span.StartLine = r.line
+			_, span.EndLine = sm.Original(r.endLine)
+			r.Range = span
+		}
+		r.Location = sm.Resolve(r.Location)
 	}
 }
 
@@ -117,47 +561,131 @@ func (g *GasOptimizer) analyzeCustomAST(ast *Node) {
 		if node.Type == "ForStatement" || node.Type == "WhileStatement" {
 			storageVars := make(map[string]int)
 			g.collectStorageReadsCustom(node, storageVars)
-			g.generateLoopReport(storageVars, fmt.Sprintf("line %d", node.Line))
+			g.generateLoopReport(storageVars, nil, fmt.Sprintf("line %d", node.Line))
 		}
 	}
 }
 
-// analyzeSolcAST analyzes the solc AST
-func (g *GasOptimizer) analyzeSolcAST(ast interface{}) {
-	astBytes, _ := json.Marshal(ast)
-	var root SolcASTNode
-	json.Unmarshal(astBytes, &root)
-	g.checkLoopsForStorageReads(root)
-	g.checkInefficientTypes(root)
-	g.checkRedundantOperations(root)
+// solcDetector is an entry of the detector registry: a check over the solc
+// AST and the rules it reports
+type solcDetector struct {
+	rules []string
+	check func(*GasOptimizer, *SolcASTNode)
+}
+
+// solcDetectors lists the solc AST checks in the order they run
+var solcDetectors = []solcDetector{
+	{[]string{RuleLoopStorageRead}, (*GasOptimizer).checkLoopsForStorageReads},
+	{[]string{RuleInefficientType}, (*GasOptimizer).checkInefficientTypes},
+	{[]string{RuleRedundantExpression}, (*GasOptimizer).checkRedundantOperations},
+	{[]string{RuleRequireString}, (*GasOptimizer).checkRequireStrings},
+	{[]string{RuleConstantCondition}, (*GasOptimizer).checkConstantConditions},
+	{[]string{RuleLoopAllocation}, (*GasOptimizer).checkLoopAllocations},
+	{[]string{RuleMemoryStructParam}, (*GasOptimizer).checkMemoryStructParams},
+	{[]string{RuleRepeatedIndexAccess}, (*GasOptimizer).checkRepeatedIndexAccess},
+	{[]string{RuleIncrementInIndex}, (*GasOptimizer).checkIncrementInIndex},
+	{[]string{RuleInlinedModifier}, (*GasOptimizer).checkInlinedModifiers},
+	{[]string{RuleExternalSelfCall}, (*GasOptimizer).checkExternalSelfCalls},
+	{[]string{RuleToggledBoolFlag}, (*GasOptimizer).checkToggledBoolFlags},
+	{[]string{RuleUnrollableLoop}, (*GasOptimizer).checkUnrollableLoops},
+	{[]string{RuleManyReturnValues}, (*GasOptimizer).checkManyReturnValues},
+	{[]string{RuleSafeMathOnChecked}, (*GasOptimizer).checkSafeMathOnChecked},
+	{[]string{RuleNewInLoop}, (*GasOptimizer).checkNewInLoops},
+	{[]string{RuleEncodeWithSignature}, (*GasOptimizer).checkEncodeWithSignature},
+	{[]string{RuleRepeatedHashKey}, (*GasOptimizer).checkRepeatedHashKeys},
+	{[]string{RuleMissingMutability}, (*GasOptimizer).checkMissingMutability},
+	{[]string{RuleLoopConstantRead}, (*GasOptimizer).checkLoopConstantReads},
+	{[]string{RuleRepeatedExternal}, (*GasOptimizer).checkRepeatedExternalCalls},
+	{[]string{RuleDuplicateZeroCheck, RuleMissingZeroCheck}, (*GasOptimizer).checkZeroAddressChecks},
+	{[]string{RuleMappingExistence}, (*GasOptimizer).checkMappingExistence},
+	{[]string{RuleLoopStorageWrite}, (*GasOptimizer).checkLoopStorageWrites},
+	{[]string{RuleBoundedLoop}, (*GasOptimizer).checkBoundedLoops},
+	{[]string{RuleLargeEventData}, (*GasOptimizer).checkLargeEventData},
+	{[]string{RuleSplitStructWrite}, (*GasOptimizer).checkStructFieldWrites},
+	{[]string{RuleConversionRoundTrip}, (*GasOptimizer).checkConversionRoundTrips},
+	{[]string{RuleUnusedParameter}, (*GasOptimizer).checkUnusedParameters},
+	{[]string{RuleRepeatedCodeLength}, (*GasOptimizer).checkRepeatedCodeLength},
+	{[]string{RuleModularArithmetic}, (*GasOptimizer).checkModularArithmetic},
+	{[]string{RuleConstructorOnly}, (*GasOptimizer).checkConstructorOnlyWrites},
+	{[]string{RuleRecomputedReturn}, (*GasOptimizer).checkRecomputedReturns},
+	{[]string{RuleLengthBeforeIndex}, (*GasOptimizer).checkLengthBeforeIndex},
+	{[]string{RuleEmitBeforeWrite}, (*GasOptimizer).checkEmitBeforeWrite},
+	{[]string{RuleNonNegativeInt}, (*GasOptimizer).checkNonNegativeInts},
+	{[]string{RuleUnsignedZeroCompare}, (*GasOptimizer).checkUnsignedZeroComparisons},
+	{[]string{RuleSharedPreamble}, (*GasOptimizer).checkSharedPreambles},
+	{[]string{RuleRepeatedDecode}, (*GasOptimizer).checkRepeatedDecodes},
+	{[]string{RuleGasStipend}, (*GasOptimizer).checkGasStipends},
+	{[]string{RuleStoragePointer}, (*GasOptimizer).checkStoragePointers},
+	{[]string{RuleUncheckedArithmetic}, (*GasOptimizer).checkUncheckedArithmetic},
+	{[]string{RuleBlockNumberTiming}, (*GasOptimizer).checkBlockNumberTiming},
+	{[]string{RuleStorageArrayNew}, (*GasOptimizer).checkStorageArrayNew},
+	{[]string{RuleEmptyLoop}, (*GasOptimizer).checkEmptyLoops},
+	{[]string{RuleLoopMappingWrite}, (*GasOptimizer).checkLoopMappingWrites},
+	{[]string{RuleConstructorParam}, (*GasOptimizer).checkConstructorParams},
+	{[]string{RuleBareRevert}, (*GasOptimizer).checkBareReverts},
+	{[]string{RuleVariableLoopStep}, (*GasOptimizer).checkVariableLoopSteps},
+	{[]string{RuleExpensiveView}, (*GasOptimizer).checkExpensiveViews},
+	{[]string{RuleBranchStorageRead}, (*GasOptimizer).checkBranchStorageReads},
+}
+
+// analyzeSolcAST analyzes the solc AST, skipping checks whose rules are
+// all excluded by --include-only
+func (g *GasOptimizer) analyzeSolcAST(root *SolcASTNode) {
+	g.collectContractMetrics(root)
+	for _, d := range solcDetectors {
+		if g.Config.runs(d.rules...) {
+			d.check(g, root)
+		}
+	}
 }
 
 // checkLoopsForStorageReads detects repeated storage reads in loops
-func (g *GasOptimizer) checkLoopsForStorageReads(ast SolcASTNode) {
-	g.walkSolcAST(ast, func(node SolcASTNode) {
+func (g *GasOptimizer) checkLoopsForStorageReads(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
 		if node.NodeType == "ForStatement" || node.NodeType == "WhileStatement" {
 			storageVars := make(map[string]int)
 			if node.Body != nil {
-				g.collectStorageReadsSolc(*node.Body, storageVars)
+				g.collectStorageReadsSolc(node.Body, storageVars)
 			}
-			g.generateLoopReport(storageVars, node.Src)
+			g.generateLoopReport(storageVars, node, node.Src)
 		}
 	})
 }
 
 // collectStorageReadsSolc collects storage reads from solc AST
-func (g *GasOptimizer) collectStorageReadsSolc(node SolcASTNode, storageVars map[string]int) {
+func (g *GasOptimizer) collectStorageReadsSolc(node *SolcASTNode, storageVars map[string]int) {
 	if node.NodeType == "VariableDeclarationStatement" && node.InitialValue != nil {
-		if iv := node.InitialValue; iv.NodeType == "IndexAccess" && iv.BaseExpression != nil && iv.IndexExpression != nil {
-			varName := iv.BaseExpression.Name + "[" + iv.IndexExpression.Name + "]"
-			storageVars[varName]++
+		if iv := node.InitialValue; iv.NodeType == "IndexAccess" {
+			if varName := indexKey(iv); varName != "" {
+				storageVars[varName]++
+			}
 		}
 	}
-	for _, child := range node.Statements {
-		g.collectStorageReadsSolc(child, storageVars)
+	if node.NodeType == "IfStatement" {
+		// The condition runs every iteration, so its reads count too
+		if node.Condition != nil {
+			g.collectIndexReads(node.Condition, func(access *SolcASTNode) {
+				if varName := indexKey(access); varName != "" {
+					storageVars[varName]++
+				}
+			})
+		}
+		for _, branch := range [...]*SolcASTNode{node.TrueBody, node.FalseBody} {
+			if branch != nil {
+				g.collectStorageReadsSolc(branch, storageVars)
+			}
+		}
 	}
-	if node.Body != nil {
-		g.collectStorageReadsSolc(*node.Body, storageVars)
+	for i := range node.Statements {
+		g.collectStorageReadsSolc(&node.Statements[i], storageVars)
+	}
+	for i := range node.Clauses {
+		g.collectStorageReadsSolc(&node.Clauses[i], storageVars)
+	}
+	for _, block := range [...]*SolcASTNode{node.Body, node.Block} {
+		if block != nil {
+			g.collectStorageReadsSolc(block, storageVars)
+		}
 	}
 }
 
@@ -172,28 +700,67 @@ func (g *GasOptimizer) collectStorageReadsCustom(node *Node, storageVars map[str
 	}
 }
 
-// generateLoopReport creates reports for repeated storage reads
-func (g *GasOptimizer) generateLoopReport(storageVars map[string]int, location string) {
+// generateLoopReport creates reports for repeated storage reads. loop is nil
+// for the custom parser, which always assumes the configured trip count.
+func (g *GasOptimizer) generateLoopReport(storageVars map[string]int, loop *SolcASTNode, location string) {
 	for varName, count := range storageVars {
-		if count > 1 {
-			savings := (count - 1) * (GasSload - GasMload)
-			g.Reports = append(g.Reports, Report{
-				Issue:      fmt.Sprintf("Variable '%s' read %d times in loop", varName, count),
-				Suggestion: fmt.Sprintf("Cache '%s' in memory before loop", varName),
-				GasSavings: savings,
-				Location:   location,
+		if count > 1 && !g.Config.isExempt(varName) {
+			// Repeated reads are warm, but a slot first read in the loop
+			// is cold, so the bound depends on access order
+			perIteration := savingsRange{(count - 1) * (g.gas.SloadWarm - g.gas.Mload), (count - 1) * (g.gas.SloadCold - g.gas.Mload)}
+			savings, note := g.loopSavings(loop, perIteration)
+			g.addReport(Report{
+				RuleID:        RuleLoopStorageRead,
+				Issue:         fmt.Sprintf("Variable '%s' read %d times in loop%s", varName, count, note),
+				Suggestion:    fmt.Sprintf("Cache '%s' in memory before loop", varName),
+				GasSavings:    savings.mid(),
+				GasSavingsMin: savings.Min,
+				GasSavingsMax: savings.Max,
+				Location:      location,
 			})
 		}
 	}
 }
 
+// savingsRange bounds a savings estimate
+type savingsRange struct {
+	Min, Max int
+}
+
+// fixedSavings is a range of exactly n
+func fixedSavings(n int) savingsRange {
+	return savingsRange{n, n}
+}
+
+// mid returns the midpoint reported as GasSavings
+func (r savingsRange) mid() int {
+	return (r.Min + r.Max) / 2
+}
+
+// loopSavings scales per-iteration savings by the loop's literal trip count
+// or, failing that, by one to 2n-1 iterations around the assumed count n,
+// so the midpoint of fixed savings matches the assumption. The upper bound
+// of perIteration (a cold slot) applies to the first iteration only, since
+// later ones find the slot warm. The note for the issue text states the
+// count used.
+func (g *GasOptimizer) loopSavings(loop *SolcASTNode, perIteration savingsRange) (savingsRange, string) {
+	if loop != nil {
+		if n, ok := literalTripCount(loop); ok && n > 0 {
+			return savingsRange{perIteration.Min * n, perIteration.Max + perIteration.Min*(n-1)}, fmt.Sprintf(" (over %d iterations)", n)
+		}
+	}
+	n := g.Config.loopIterations()
+	return savingsRange{perIteration.Min, perIteration.Max + perIteration.Min*(2*n-2)}, fmt.Sprintf(" (assuming %d iterations)", n)
+}
+
 // checkInefficientTypes detects inefficient type usage
-func (g *GasOptimizer) checkInefficientTypes(ast SolcASTNode) {
-	g.walkSolcAST(ast, func(node SolcASTNode) {
+func (g *GasOptimizer) checkInefficientTypes(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
 		if node.NodeType == "VariableDeclaration" && node.TypeName != nil {
 			typeName := node.TypeName.Name
 			if typeName == "uint8" || typeName == "uint16" || typeName == "uint32" {
-				g.Reports = append(g.Reports, Report{
+				g.addReport(Report{
+					RuleID:     RuleInefficientType,
 					Issue:      fmt.Sprintf("Inefficient type '%s' used for variable '%s'", typeName, node.Name),
 					Suggestion: "Use 'uint256' to avoid packing overhead unless tightly packed in a struct",
 					GasSavings: 200,
@@ -205,14 +772,20 @@ func (g *GasOptimizer) checkInefficientTypes(ast SolcASTNode) {
 }
 
 // checkRedundantOperations detects redundant computations
-func (g *GasOptimizer) checkRedundantOperations(ast SolcASTNode) {
-	g.walkSolcAST(ast, func(node SolcASTNode) {
+func (g *GasOptimizer) checkRedundantOperations(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
 		if node.NodeType == "FunctionDefinition" && node.Body != nil {
 			exprMap := make(map[string]int)
-			g.collectExpressions(*node.Body, exprMap)
-			for expr, count := range exprMap {
-				if count > 1 {
-					g.Reports = append(g.Reports, Report{
+			g.collectExpressions(node.Body, exprMap)
+			exprs := make([]string, 0, len(exprMap))
+			for expr := range exprMap {
+				exprs = append(exprs, expr)
+			}
+			sort.Strings(exprs)
+			for _, expr := range exprs {
+				if count := exprMap[expr]; count > 1 {
+					g.addReport(Report{
+						RuleID:     RuleRedundantExpression,
 						Issue:      fmt.Sprintf("Expression '%s' computed %d times", expr, count),
 						Suggestion: "Cache the result in a local variable",
 						GasSavings: count * 50,
@@ -225,82 +798,141 @@ func (g *GasOptimizer) checkRedundantOperations(ast SolcASTNode) {
 }
 
 // collectExpressions collects expressions for redundancy check
-func (g *GasOptimizer) collectExpressions(node SolcASTNode, exprMap map[string]int) {
-	if node.NodeType == "BinaryOperation" && node.LeftExpression != nil && node.RightExpression != nil {
-		var leftVal, rightVal string
-		if node.LeftExpression.Name != "" {
-			leftVal = node.LeftExpression.Name
-		} else if node.LeftExpression.Value != "" {
-			leftVal = node.LeftExpression.Value
-		}
-		if node.RightExpression.Value != "" {
-			rightVal = node.RightExpression.Value // Literal value (e.g., "2")
-		} else if node.RightExpression.Name != "" {
-			rightVal = node.RightExpression.Name // Identifier
-		}
-		if leftVal != "" && rightVal != "" {
-			expr := fmt.Sprintf("%s %s %s", leftVal, node.Operator, rightVal)
+func (g *GasOptimizer) collectExpressions(node *SolcASTNode, exprMap map[string]int) {
+	if node.NodeType == "BinaryOperation" {
+		if expr := normalizeExpr(node); expr != "" {
 			exprMap[expr]++
 		}
 	}
 	// Recursively check nested expressions
+	if node.NodeType == "TupleExpression" && len(node.Components) == 1 && node.Components[0] != nil {
+		g.collectExpressions(node.Components[0], exprMap)
+	}
 	if node.LeftExpression != nil {
-		g.collectExpressions(*node.LeftExpression, exprMap)
+		g.collectExpressions(node.LeftExpression, exprMap)
 	}
 	if node.RightExpression != nil {
-		g.collectExpressions(*node.RightExpression, exprMap)
+		g.collectExpressions(node.RightExpression, exprMap)
 	}
 	if node.NodeType == "VariableDeclarationStatement" && node.InitialValue != nil {
-		g.collectExpressions(*node.InitialValue, exprMap)
+		g.collectExpressions(node.InitialValue, exprMap)
 	}
 	if node.NodeType == "Return" && node.Expression != nil {
-		g.collectExpressions(*node.Expression, exprMap)
+		g.collectExpressions(node.Expression, exprMap)
+	}
+	for i := range node.Statements {
+		g.collectExpressions(&node.Statements[i], exprMap)
 	}
-	for _, stmt := range node.Statements {
-		g.collectExpressions(stmt, exprMap)
+	for i := range node.Clauses {
+		g.collectExpressions(&node.Clauses[i], exprMap)
+	}
+	if node.Block != nil {
+		g.collectExpressions(node.Block, exprMap)
 	}
 }
 
-// walkSolcAST recursively walks the solc AST
-func (g *GasOptimizer) walkSolcAST(node SolcASTNode, fn func(SolcASTNode)) {
-	fn(node)
-	for _, child := range node.Nodes {
-		g.walkSolcAST(child, fn)
+// commutativeOps are binary operators whose operands may be swapped
+var commutativeOps = map[string]bool{
+	"+": true, "*": true, "==": true, "!=": true, "&": true, "|": true, "^": true, "&&": true, "||": true,
+}
+
+// normalizeExpr renders an expression canonically so that semantically
+// equal forms share a key: parentheses are dropped and the operands of
+// commutative operators are sorted, so a + b, b + a and (b) + (a) all give
+// "a + b". Nested operations are bracketed to keep precedence. Returns ""
+// for expressions it cannot render, such as function calls.
+func normalizeExpr(node *SolcASTNode) string {
+	switch node.NodeType {
+	case "TupleExpression":
+		if len(node.Components) == 1 && node.Components[0] != nil {
+			return normalizeExpr(node.Components[0])
+		}
+	case "BinaryOperation":
+		if node.LeftExpression == nil || node.RightExpression == nil {
+			return ""
+		}
+		left, right := normalizeOperand(node.LeftExpression), normalizeOperand(node.RightExpression)
+		if left == "" || right == "" {
+			return ""
+		}
+		if commutativeOps[node.Operator] && right < left {
+			left, right = right, left
+		}
+		return left + " " + node.Operator + " " + right
+	default:
+		return indexKey(node)
 	}
-	if node.Body != nil {
-		g.walkSolcAST(*node.Body, fn)
+	return ""
+}
+
+// normalizeOperand is normalizeExpr for an operand, bracketing nested
+// binary operations
+func normalizeOperand(node *SolcASTNode) string {
+	node = unparen(node)
+	expr := normalizeExpr(node)
+	if expr != "" && node.NodeType == "BinaryOperation" {
+		return "(" + expr + ")"
 	}
-	for _, stmt := range node.Statements {
-		g.walkSolcAST(stmt, fn)
+	return expr
+}
+
+// unparen strips the one-element tuples that parentheses produce, keeping
+// one-element array literals such as [x]
+func unparen(node *SolcASTNode) *SolcASTNode {
+	for node.NodeType == "TupleExpression" && !node.IsInlineArray && len(node.Components) == 1 && node.Components[0] != nil {
+		node = node.Components[0]
 	}
+	return node
 }
 
-// PrintReports displays the analysis results
-func (g *GasOptimizer) PrintReports() {
-	if len(g.Reports) == 0 {
-		fmt.Println("No gas optimization opportunities found.")
+// walkSolcAST recursively walks the solc AST
+func (g *GasOptimizer) walkSolcAST(node *SolcASTNode, fn func(*SolcASTNode)) {
+	g.inspectSolcAST(node, func(n *SolcASTNode) bool {
+		fn(n)
+		return true
+	})
+}
+
+// inspectSolcAST walks the solc AST like walkSolcAST, skipping the children
+// of any node for which fn returns false
+func (g *GasOptimizer) inspectSolcAST(node *SolcASTNode, fn func(*SolcASTNode) bool) {
+	if !fn(node) {
 		return
 	}
-	for i, r := range g.Reports {
-		fmt.Printf("Report %d:\n", i+1)
-		fmt.Printf("  Issue: %s\n", r.Issue)
-		fmt.Printf("  Suggestion: %s\n", r.Suggestion)
-		fmt.Printf("  Gas Savings: %d\n", r.GasSavings)
-		fmt.Printf("  Location: %s\n\n", r.Location)
+	for i := range node.Nodes {
+		g.inspectSolcAST(&node.Nodes[i], fn)
 	}
-}
-
-func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: gasoptimizer <solidity_file>")
+	if node.Body != nil {
+		g.inspectSolcAST(node.Body, fn)
 	}
-
-	filePath := os.Args[1]
-	optimizer, err := NewGasOptimizer(filePath)
-	if err != nil {
-		log.Fatalf("Error: %v", err)
+	for i := range node.Statements {
+		g.inspectSolcAST(&node.Statements[i], fn)
+	}
+	for _, expr := range [...]*SolcASTNode{node.Expression, node.InitialValue, node.LeftExpression,
+		node.RightExpression, node.BaseExpression, node.IndexExpression,
+		node.Condition, node.TrueBody, node.FalseBody,
+		node.LeftHandSide, node.RightHandSide, node.SubExpression,
+		node.InitializationExpression, node.LoopExpression, node.ExternalCall, node.Block} {
+		if expr != nil {
+			g.inspectSolcAST(expr, fn)
+		}
+	}
+	for i := range node.Arguments {
+		g.inspectSolcAST(&node.Arguments[i], fn)
+	}
+	for i := range node.Options {
+		g.inspectSolcAST(&node.Options[i], fn)
 	}
+	for _, component := range node.Components {
+		if component != nil {
+			g.inspectSolcAST(component, fn)
+		}
+	}
+	for i := range node.Clauses {
+		g.inspectSolcAST(&node.Clauses[i], fn)
+	}
+}
 
-	optimizer.Analyze()
-	optimizer.PrintReports()
+func main() {
+	os.Exit(run(os.Args[1:]))
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// analyzeJSON analyzes a solc compact JSON AST of x.sol with the default
+// config and returns the reports of rule
+func analyzeJSON(t *testing.T, ast, rule string) []Report {
+	t.Helper()
+	root, err := decodeSolcAST("x.sol", []byte(ast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &GasOptimizer{Path: "x.sol", AST: root, Reports: []Report{}}
+	g.Analyze()
+	return reportsOf(g.Reports, rule)
+}
+
+// analyzeSource analyzes source with the custom parser and returns the
+// reports of rule
+func analyzeSource(t *testing.T, source, rule string) []Report {
+	t.Helper()
+	g := &GasOptimizer{Path: "x.sol", Source: source, AST: NewParser(source).Parse(), Reports: []Report{}}
+	g.Analyze()
+	return reportsOf(g.Reports, rule)
+}
+
+// reportsOf returns the reports of rule
+func reportsOf(reports []Report, rule string) []Report {
+	var out []Report
+	for _, r := range reports {
+		if r.RuleID == rule {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// loopWithIfJSON is for (...) { if (data[i] > 0) { uint v = <read>; } }
+// where read is a JSON expression
+func loopWithIfJSON(read string) string {
+	return `{"nodeType":"SourceUnit","src":"0:300:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:300:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","src":"10:280:0","body":{"nodeType":"Block","src":"20:260:0","statements":[
+{"nodeType":"ForStatement","src":"30:240:0","body":{"nodeType":"Block","src":"60:200:0","statements":[
+{"nodeType":"IfStatement","src":"70:180:0",
+ "condition":{"nodeType":"BinaryOperation","operator":">","src":"74:11:0",
+  "leftExpression":{"nodeType":"IndexAccess","src":"74:7:0","baseExpression":{"nodeType":"Identifier","name":"data","src":"74:4:0"},"indexExpression":{"nodeType":"Identifier","name":"i","src":"79:1:0"}},
+  "rightExpression":{"nodeType":"Literal","kind":"number","value":"0","src":"84:1:0"}},
+ "trueBody":{"nodeType":"Block","src":"90:100:0","statements":[
+  {"nodeType":"VariableDeclarationStatement","src":"100:20:0",
+   "declarations":[{"nodeType":"VariableDeclaration","name":"v","id":9,"src":"100:6:0"}],
+   "initialValue":` + read + `}]}}]}}]}}]}]}`
+}
+
+func TestLoopStorageReadInIfCondition(t *testing.T) {
+	t.Run("solc", func(t *testing.T) {
+		dataI := `{"nodeType":"IndexAccess","src":"108:7:0","baseExpression":{"nodeType":"Identifier","name":"data","src":"108:4:0"},"indexExpression":{"nodeType":"Identifier","name":"i","src":"113:1:0"}}`
+		reports := analyzeJSON(t, loopWithIfJSON(dataI), RuleLoopStorageRead)
+		if len(reports) != 1 || !strings.HasPrefix(reports[0].Issue, "Variable 'data[i]' read 2 times in loop") {
+			t.Errorf("reports = %+v, want one for data[i] read 2 times", reports)
+		}
+
+		literal := `{"nodeType":"Literal","kind":"number","value":"1","src":"108:1:0"}`
+		if reports := analyzeJSON(t, loopWithIfJSON(literal), RuleLoopStorageRead); len(reports) != 0 {
+			t.Errorf("single read in the condition: reports = %+v, want none", reports)
+		}
+	})
+	t.Run("custom", func(t *testing.T) {
+		source := "for (uint i = 0; i < n; i++) {\n  if (cfg.limit > i) { total = cfg.limit; }\n}\n"
+		reports := analyzeSource(t, source, RuleLoopStorageRead)
+		if len(reports) != 1 || !strings.HasPrefix(reports[0].Issue, "Variable 'cfg.limit' read 2 times in loop") {
+			t.Errorf("reports = %+v, want one for cfg.limit read 2 times", reports)
+		}
+	})
+}
+
+// largeSolcJSON is a contract of n functions, each looping over storage
+// reads in if statements as loopWithIfJSON does
+func largeSolcJSON(n int) []byte {
+	var b strings.Builder
+	b.WriteString(`{"nodeType":"SourceUnit","src":"0:300:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:300:0","nodes":[`)
+	fn := loopWithIfJSON(`{"nodeType":"IndexAccess","src":"108:7:0","baseExpression":{"nodeType":"Identifier","name":"data","src":"108:4:0"},"indexExpression":{"nodeType":"Identifier","name":"i","src":"113:1:0"}}`)
+	fn = fn[strings.Index(fn, `{"nodeType":"FunctionDefinition"`) : len(fn)-len(`]}]}`)]
+	for i := range n {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(fn)
+	}
+	b.WriteString(`]}]}`)
+	return []byte(b.String())
+}
+
+func BenchmarkDecodeSolcAST(b *testing.B) {
+	data := largeSolcJSON(1000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for range b.N {
+		if _, err := decodeSolcAST("x.sol", data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAnalyzeSolcAST(b *testing.B) {
+	root, err := decodeSolcAST("x.sol", largeSolcJSON(1000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		g := &GasOptimizer{Path: "x.sol", AST: root, Reports: []Report{}}
+		g.Analyze()
+	}
+}
+
+func BenchmarkWalkSolcAST(b *testing.B) {
+	root, err := decodeSolcAST("x.sol", largeSolcJSON(1000))
+	if err != nil {
+		b.Fatal(err)
+	}
+	g := &GasOptimizer{}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		nodes := 0
+		g.walkSolcAST(root, func(*SolcASTNode) { nodes++ })
+	}
+}
+
+// dataIJSON is the JSON of the storage read data[i]
+const dataIJSON = `{"nodeType":"IndexAccess","src":"108:7:0","baseExpression":{"nodeType":"Identifier","name":"data","src":"108:4:0","typeDescriptions":{"typeIdentifier":"t_array$_t_uint256_$dyn_storage"}},"indexExpression":{"nodeType":"Identifier","name":"i","src":"113:1:0"}}`
+
+func TestTryStatementClausesAreAnalyzed(t *testing.T) {
+	read := `{"nodeType":"VariableDeclarationStatement","src":"100:20:0","declarations":[{"nodeType":"VariableDeclaration","name":"v","src":"100:6:0"}],"initialValue":` + dataIJSON + `}`
+	reads := strings.Repeat(read+",", 2) + read
+	ast := `{"nodeType":"SourceUnit","src":"0:400:0","nodes":[{"nodeType":"ContractDefinition","name":"C","src":"0:400:0","nodes":[
+{"nodeType":"FunctionDefinition","name":"f","src":"10:380:0","body":{"nodeType":"Block","src":"20:360:0","statements":[
+{"nodeType":"TryStatement","src":"30:340:0",
+ "externalCall":{"nodeType":"FunctionCall","src":"34:10:0","expression":{"nodeType":"MemberAccess","memberName":"g","src":"34:6:0","expression":{"nodeType":"Identifier","name":"other","src":"34:5:0"}}},
+ "clauses":[
+  {"nodeType":"TryCatchClause","src":"50:100:0","block":{"nodeType":"Block","src":"50:100:0","statements":[` + reads + `]}},
+  {"nodeType":"TryCatchClause","src":"160:200:0","block":{"nodeType":"Block","src":"160:200:0","statements":[
+   {"nodeType":"WhileStatement","src":"170:180:0","body":{"nodeType":"Block","src":"180:160:0","statements":[` + read + `,` + read + `]}}]}}]}]}}]}]}`
+
+	root, err := decodeSolcAST("x.sol", []byte(ast))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &GasOptimizer{Path: "x.sol", AST: root, Reports: []Report{}}
+	g.Analyze()
+	if reports := reportsOf(g.Reports, RuleRepeatedIndexAccess); len(reports) != 1 || reports[0].Issue != "'data[i]' is read 5 times in 'f'" {
+		t.Errorf("%s reports = %+v, want data[i] read 5 times across both clauses", RuleRepeatedIndexAccess, reports)
+	}
+	if reports := reportsOf(g.Reports, RuleLoopStorageRead); len(reports) != 1 || !strings.HasPrefix(reports[0].Issue, "Variable 'data[i]' read 2 times in loop") {
+		t.Errorf("%s reports = %+v, want one for the loop in the catch clause", RuleLoopStorageRead, reports)
+	}
+}
+
+func TestNormalizeExpr(t *testing.T) {
+	id := func(name string) *SolcASTNode { return &SolcASTNode{NodeType: "Identifier", Name: name} }
+	op := func(l *SolcASTNode, operator string, r *SolcASTNode) *SolcASTNode {
+		return &SolcASTNode{NodeType: "BinaryOperation", Operator: operator, LeftExpression: l, RightExpression: r}
+	}
+	paren := func(n *SolcASTNode) *SolcASTNode {
+		return &SolcASTNode{NodeType: "TupleExpression", Components: []*SolcASTNode{n}}
+	}
+	a, b, c := id("a"), id("b"), id("c")
+	tests := []struct {
+		name  string
+		x, y  *SolcASTNode
+		equal bool
+	}{
+		{"a+b vs b+a", op(a, "+", b), op(b, "+", a), true},
+		{"a*b vs b*a", op(a, "*", b), op(b, "*", a), true},
+		{"parenthesized operands", op(paren(a), "+", paren(b)), op(b, "+", a), true},
+		{"parenthesized expression", paren(op(a, "+", b)), op(a, "+", b), true},
+		{"nested commutative", op(op(a, "+", b), "*", c), op(c, "*", paren(op(b, "+", a))), true},
+		{"a-b vs b-a", op(a, "-", b), op(b, "-", a), false},
+		{"a/b vs b/a", op(a, "/", b), op(b, "/", a), false},
+		{"a<b vs b<a", op(a, "<", b), op(b, "<", a), false},
+		{"grouping kept", op(op(a, "-", b), "-", c), op(a, "-", paren(op(b, "-", c))), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y := normalizeExpr(tt.x), normalizeExpr(tt.y)
+			if x == "" || y == "" {
+				t.Fatalf("normalizeExpr gave %q and %q, want keys", x, y)
+			}
+			if (x == y) != tt.equal {
+				t.Errorf("keys %q and %q: equal = %v, want %v", x, y, x == y, tt.equal)
+			}
+		})
+	}
+}
+
+// multiLineSource has the expression a +\n b spanning lines 3 and 4
+const multiLineSource = `contract C {
+    function f() public {
+        total = a +
+            b;
+    }
+}
+`
+
+// multiLineOptimizer holds one report on the span of a +\n b in
+// multiLineSource, with locations resolved
+func multiLineOptimizer(t testing.TB) *GasOptimizer {
+	start := strings.Index(multiLineSource, "a +")
+	end := strings.Index(multiLineSource, "b;") + len("b")
+	g := &GasOptimizer{Path: "x.sol", Source: multiLineSource, Reports: []Report{{
+		RuleID:   RuleRedundantExpression,
+		Location: fmt.Sprintf("%d:%d:0", start, end-start),
+	}}}
+	g.resolveLocations()
+	return g
+}
+
+func TestResolveLocationsMultiLineRange(t *testing.T) {
+	r := multiLineOptimizer(t).Reports[0]
+	want := Range{StartLine: 3, StartColumn: 17, EndLine: 4, EndColumn: 14}
+	if r.Range == nil || *r.Range != want {
+		t.Fatalf("Range = %+v, want %+v", r.Range, want)
+	}
+	if r.Location != "x.sol:3" {
+		t.Errorf("Location = %q, want x.sol:3", r.Location)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ContractMetrics summarizes one contract for trend dashboards
+type ContractMetrics struct {
+	Contract                string `json:"contract"`
+	NumFunctions            int    `json:"numFunctions"`
+	NumStorageVars          int    `json:"numStorageVars"`
+	EstimatedOptimizableGas int    `json:"estimatedOptimizableGas"`
+	Score                   int    `json:"score"` // 100 = nothing to optimize, falling as savings per KB of source grow
+
+	start, end int // source span, used to attribute reports
+	functions  []functionMetrics
+}
+
+// functionMetrics attributes savings to one function for snapshots
+type functionMetrics struct {
+	Signature  string // name(type,...), or the kind for constructors, fallback and receive
+	Savings    int
+	start, end int
+}
+
+// functionSignature renders fn as name(type,...) to tell overloads apart
+func functionSignature(fn *SolcASTNode) string {
+	name := fn.Name
+	if name == "" {
+		name = fn.Kind
+	}
+	var types []string
+	if fn.Parameters != nil {
+		for i := range fn.Parameters.Parameters {
+			types = append(types, declTypeString(&fn.Parameters.Parameters[i]))
+		}
+	}
+	return name + "(" + strings.Join(types, ",") + ")"
+}
+
+// collectContractMetrics counts functions and storage variables of every
+// contract in the solc AST
+func (g *GasOptimizer) collectContractMetrics(ast *SolcASTNode) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "ContractDefinition" {
+			return
+		}
+		m := ContractMetrics{Contract: node.Name}
+		if start, length, ok := parseSrc(node.Src); ok {
+			m.start, m.end = start, start+length
+		}
+		for i := range node.Nodes {
+			switch member := &node.Nodes[i]; {
+			case member.NodeType == "FunctionDefinition":
+				m.NumFunctions++
+				f := functionMetrics{Signature: functionSignature(member)}
+				if start, length, ok := parseSrc(member.Src); ok {
+					f.start, f.end = start, start+length
+				}
+				m.functions = append(m.functions, f)
+			case isStorageVariable(member):
+				m.NumStorageVars++
+			}
+		}
+		g.Metrics = append(g.Metrics, m)
+	})
+}
+
+// scoreMetrics attributes report savings to the contract containing them
+// and derives each contract's score; reports also learn their enclosing
+// function. It starts over on every call, so filters dropping reports,
+// such as --since and --ci-base, call it again to keep the metrics and
+// snapshot to the findings they kept.
+func (g *GasOptimizer) scoreMetrics() {
+	for i := range g.Metrics {
+		m := &g.Metrics[i]
+		m.EstimatedOptimizableGas = 0
+		for j := range m.functions {
+			m.functions[j].Savings = 0
+		}
+		for k := range g.Reports {
+			r := &g.Reports[k]
+			span := r.Location
+			if r.Src != "" {
+				span = r.Src // resolved already
+			}
+			start, _, ok := parseSrc(span)
+			if !ok || start < m.start || start >= m.end {
+				continue
+			}
+			m.EstimatedOptimizableGas += r.GasSavings
+			r.function = m.Contract
+			for j := range m.functions {
+				if f := &m.functions[j]; start >= f.start && start < f.end {
+					f.Savings += r.GasSavings
+					r.function = m.Contract + "." + f.Signature
+				}
+			}
+		}
+		size := m.end - m.start
+		if size <= 0 {
+			size = 1
+		}
+		gasPerKB := m.EstimatedOptimizableGas * 1000 / size
+		m.Score = 100 * 1000 / (1000 + gasPerKB)
+	}
+}
+
+// PrintMetrics displays per-contract metrics
+func (g *GasOptimizer) PrintMetrics() {
+	if len(g.Metrics) == 0 {
+		fmt.Println("No contract metrics available (requires solc).")
+		return
+	}
+	for _, m := range g.Metrics {
+		fmt.Printf("Contract %s:\n", m.Contract)
+		fmt.Printf("  Functions: %d\n", m.NumFunctions)
+		fmt.Printf("  Storage Variables: %d\n", m.NumStorageVars)
+		fmt.Printf("  Optimizable Gas: %d\n", m.EstimatedOptimizableGas)
+		fmt.Printf("  Score: %d/100\n\n", m.Score)
+	}
+}
+
+// Summary totals the findings of an analysis
+type Summary struct {
+	Findings   int                          `json:"findings"`
+	GasSavings int                          `json:"gasSavings"`
+	ByCategory map[Category]CategorySummary `json:"byCategory"`
+	ByArea     map[Area]CategorySummary     `json:"byArea"`
+}
+
+// CategorySummary totals the findings of one rule category or area
+type CategorySummary struct {
+	Findings   int `json:"findings"`
+	GasSavings int `json:"gasSavings"`
+	Percent    int `json:"percent"` // share of the total savings, rounded
+}
+
+// Summarize totals the reports, overall, by category and by area
+func (g *GasOptimizer) Summarize() Summary {
+	s := Summary{ByCategory: make(map[Category]CategorySummary), ByArea: make(map[Area]CategorySummary)}
+	for _, r := range g.Reports {
+		s.ByCategory[r.Category] = s.ByCategory[r.Category].add(r)
+		s.ByArea[r.Area] = s.ByArea[r.Area].add(r)
+		s.Findings++
+		s.GasSavings += r.GasSavings
+	}
+	if s.GasSavings > 0 {
+		for category, c := range s.ByCategory {
+			s.ByCategory[category] = c.percentOf(s.GasSavings)
+		}
+		for area, c := range s.ByArea {
+			s.ByArea[area] = c.percentOf(s.GasSavings)
+		}
+	}
+	return s
+}
+
+// add counts r into c
+func (c CategorySummary) add(r Report) CategorySummary {
+	c.Findings++
+	c.GasSavings += r.GasSavings
+	return c
+}
+
+// percentOf sets c's share of total
+func (c CategorySummary) percentOf(total int) CategorySummary {
+	c.Percent = (c.GasSavings*100 + total/2) / total
+	return c
+}
+
+// Breakdown renders the savings by area, largest share first, e.g.
+// "storage: 62%, loops: 25%, types: 13%"; areas without savings are left
+// out. Areas rather than categories, since nearly all savings are gas.
+func (s Summary) Breakdown() string {
+	areas := make([]Area, 0, len(s.ByArea))
+	for area, c := range s.ByArea {
+		if c.GasSavings > 0 {
+			areas = append(areas, area)
+		}
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		a, b := s.ByArea[areas[i]], s.ByArea[areas[j]]
+		if a.GasSavings != b.GasSavings {
+			return a.GasSavings > b.GasSavings
+		}
+		return areas[i] < areas[j]
+	})
+	parts := make([]string, len(areas))
+	for i, area := range areas {
+		parts[i] = fmt.Sprintf("%s: %d%%", area, s.ByArea[area].Percent)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestRulesHaveAreas(t *testing.T) {
+	areas := map[Area]bool{AreaStorage: true, AreaLoops: true, AreaTypes: true, AreaComputation: true,
+		AreaCalls: true, AreaErrors: true, AreaEvents: true, AreaDeployment: true}
+	for _, r := range Rules {
+		if !areas[r.Area] {
+			t.Errorf("%s has area %q", r.ID, r.Area)
+		}
+	}
+}
+
+func TestSummaryBreakdown(t *testing.T) {
+	g := &GasOptimizer{Reports: []Report{
+		{RuleID: RuleLoopStorageRead, GasSavings: 6200},
+		{RuleID: RuleBranchStorageRead, GasSavings: 0},
+		{RuleID: RuleLoopAllocation, GasSavings: 2500},
+		{RuleID: RuleInefficientType, GasSavings: 1300},
+		{RuleID: RuleMappingExistence},
+	}}
+	g.applyRules()
+	s := g.Summarize()
+	if s.Findings != 5 || s.GasSavings != 10000 {
+		t.Errorf("totals = %d findings, %d gas; want 5, 10000", s.Findings, s.GasSavings)
+	}
+	if want := (CategorySummary{Findings: 3, GasSavings: 6200, Percent: 62}); s.ByArea[AreaStorage] != want {
+		t.Errorf("storage = %+v, want %+v", s.ByArea[AreaStorage], want)
+	}
+	if want := (CategorySummary{Findings: 4, GasSavings: 10000, Percent: 100}); s.ByCategory[CategoryGas] != want {
+		t.Errorf("gas = %+v, want %+v", s.ByCategory[CategoryGas], want)
+	}
+	if got, want := s.Breakdown(), "storage: 62%, loops: 25%, types: 13%"; got != want {
+		t.Errorf("Breakdown() = %q, want %q", got, want)
+	}
+	if got := (&GasOptimizer{}).Summarize().Breakdown(); got != "" {
+		t.Errorf("Breakdown() without findings = %q, want empty", got)
+	}
+}
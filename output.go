@@ -0,0 +1,720 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// reportWriter renders the analysis results in one format
+type reportWriter func(g *GasOptimizer, w io.Writer) error
+
+// formats maps --format/--report names to writers
+var formats = map[string]reportWriter{
+	"text":        (*GasOptimizer).WriteText,
+	"json":        (*GasOptimizer).WriteJSON,
+	"sarif":       (*GasOptimizer).WriteSARIF,
+	"junit":       (*GasOptimizer).WriteJUnit,
+	"codeclimate": (*GasOptimizer).WriteCodeClimate,
+	"table":       (*GasOptimizer).WriteTable,
+	"compact":     (*GasOptimizer).WriteCompact,
+	"snapshot":    (*GasOptimizer).WriteSnapshot,
+}
+
+// formatNames lists the supported formats for help text
+func formatNames() string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// DefaultSort is the --sort mode used when none is given: by location,
+// so output is stable and diffs cleanly between runs
+const DefaultSort = "location"
+
+// sortModes maps --sort names to orderings of the reports
+var sortModes = map[string]func(reports []Report){
+	"location": sortByLocation,
+	"savings":  sortByKey(func(a, b *Report) bool { return a.GasSavings > b.GasSavings }),
+	"severity": sortByKey(func(a, b *Report) bool { return severityRank[a.Severity] > severityRank[b.Severity] }),
+	"rule":     sortByKey(func(a, b *Report) bool { return a.RuleID < b.RuleID }),
+	"roi":      sortByKey(roiLess),
+}
+
+// sortNames lists the --sort modes for help text
+func sortNames() string {
+	names := make([]string, 0, len(sortModes))
+	for name := range sortModes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// sortByLocation orders reports by file, line and column, then rule
+func sortByLocation(reports []Report) {
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := &reports[i], &reports[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.line != b.line {
+			return a.line < b.line
+		}
+		if ac, bc := a.column(), b.column(); ac != bc {
+			return ac < bc
+		}
+		return a.RuleID < b.RuleID
+	})
+}
+
+// column returns the report's start column, or 0 if it has none
+func (r *Report) column() int {
+	if r.Range == nil {
+		return 0
+	}
+	return r.Range.StartColumn
+}
+
+// sortByKey orders reports by less, breaking ties by location
+func sortByKey(less func(a, b *Report) bool) func(reports []Report) {
+	return func(reports []Report) {
+		sortByLocation(reports)
+		sort.SliceStable(reports, func(i, j int) bool { return less(&reports[i], &reports[j]) })
+	}
+}
+
+// roiLess orders reports by savings per unit of effort, best first, so
+// cheap fixes with large savings come before refactorings
+func roiLess(a, b *Report) bool {
+	// a/wa > b/wb without integer division
+	return a.GasSavings*effortWeight[b.Effort] > b.GasSavings*effortWeight[a.Effort]
+}
+
+// PrintReports displays the analysis results
+func (g *GasOptimizer) PrintReports() {
+	g.WriteText(os.Stdout)
+}
+
+// WriteText writes the human-readable report blocks
+func (g *GasOptimizer) WriteText(w io.Writer) error {
+	if len(g.Reports) == 0 {
+		_, err := fmt.Fprintln(w, "No gas optimization opportunities found.")
+		return err
+	}
+	for i, r := range g.Reports {
+		if err := g.writeReportBlock(w, i+1, r); err != nil {
+			return err
+		}
+	}
+	return g.writeSummaryLine(w)
+}
+
+// writeSummaryLine writes the finding count and total savings, broken down
+// by area when any savings are estimated
+func (g *GasOptimizer) writeSummaryLine(w io.Writer) error {
+	s := g.Summarize()
+	noun := "findings"
+	if s.Findings == 1 {
+		noun = "finding"
+	}
+	line := fmt.Sprintf("Total: %d %s, %d gas", s.Findings, noun, s.GasSavings)
+	if breakdown := s.Breakdown(); breakdown != "" {
+		line += " (" + breakdown + ")"
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// writeReportBlock writes one numbered report in text form, with its
+// source snippet when context lines were requested
+func (g *GasOptimizer) writeReportBlock(w io.Writer, n int, r Report) error {
+	fmt.Fprintf(w, "Report %d:\n", n)
+	if r.Category != "" && r.Category != CategoryGas {
+		fmt.Fprintf(w, "  Rule: %s (%s, %s)\n", r.RuleID, r.Level, r.Category)
+	} else {
+		fmt.Fprintf(w, "  Rule: %s (%s)\n", r.RuleID, r.Level)
+	}
+	fmt.Fprintf(w, "  Severity: %s (confidence: %s, effort: %s)\n", r.Severity, r.Confidence, r.Effort)
+	fmt.Fprintf(w, "  Issue: %s\n", r.Issue)
+	fmt.Fprintf(w, "  Suggestion: %s\n", r.Suggestion)
+	if r.GasSavingsMin != r.GasSavingsMax {
+		fmt.Fprintf(w, "  Gas Savings: %d (range %d-%d)\n", r.GasSavings, r.GasSavingsMin, r.GasSavingsMax)
+	} else {
+		fmt.Fprintf(w, "  Gas Savings: %d\n", r.GasSavings)
+	}
+	fmt.Fprintf(w, "  Location: %s\n", r.Location)
+	if g.Context > 0 && r.SourceSnippet != "" {
+		fmt.Fprintln(w, "  Source:")
+		for _, line := range strings.Split(r.SourceSnippet, "\n") {
+			fmt.Fprintf(w, "    | %s\n", line)
+		}
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// defaultTableWidth is the terminal width assumed by WriteTable when
+// $COLUMNS is unset
+const defaultTableWidth = 120
+
+// tableWidth returns the terminal width from $COLUMNS
+func tableWidth() int {
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultTableWidth
+}
+
+// WriteTable writes one aligned row per report, in the order of
+// g.Reports as --sort left it, truncating issues so rows fit the terminal
+// width
+func (g *GasOptimizer) WriteTable(w io.Writer) error {
+	if len(g.Reports) == 0 {
+		return g.WriteText(w)
+	}
+	reports := g.Reports
+
+	const padding = 2
+	header := [...]string{"SEVERITY", "SAVINGS", "RULE", "LOCATION", "ISSUE"}
+	rows := make([][len(header)]string, len(reports))
+	widths := [len(header)]int{}
+	for i, r := range reports {
+		rows[i] = [len(header)]string{string(r.Severity), strconv.Itoa(r.GasSavings), r.RuleID, r.Location, r.Issue}
+	}
+	for col := range widths {
+		widths[col] = len(header[col])
+		for _, row := range rows {
+			widths[col] = max(widths[col], len(row[col]))
+		}
+	}
+	// The issue column gets whatever the other columns leave, but never
+	// so little that issues become unreadable
+	issueWidth := tableWidth()
+	for _, width := range widths[:len(header)-1] {
+		issueWidth -= width + padding
+	}
+	issueWidth = max(issueWidth, 30)
+
+	tw := tabwriter.NewWriter(w, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header[:], "\t"))
+	for _, row := range rows {
+		if len(row[4]) > issueWidth {
+			row[4] = truncate(row[4], issueWidth-3)
+		}
+		fmt.Fprintln(tw, strings.Join(row[:], "\t"))
+	}
+	return tw.Flush()
+}
+
+// WriteCompact writes one "path:line:col: [SEVERITY] RULE savings=N issue"
+// line per report, in analysis order, for grep and awk. Findings without
+// a column, such as those of the fallback parser, use column 1.
+func (g *GasOptimizer) WriteCompact(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, r := range g.Reports {
+		file, line, col := r.file, max(r.line, 1), 1
+		if r.Range != nil {
+			col = r.Range.StartColumn
+		}
+		if file == "" {
+			file = g.Path
+		}
+		fmt.Fprintf(bw, "%s:%d:%d: [%s] %s savings=%d %s\n", file, line, col, strings.ToUpper(string(r.Severity)), r.RuleID, r.GasSavings, r.Issue)
+	}
+	return bw.Flush()
+}
+
+// collapseShown is how many findings per rule --collapse prints in full
+const collapseShown = 3
+
+// ruleGroup is one rule's findings, in the order they were reported
+type ruleGroup struct {
+	RuleID  string
+	Reports []Report
+}
+
+// groupByRule groups reports by rule, ordering groups by first appearance
+func groupByRule(reports []Report) []ruleGroup {
+	var groups []ruleGroup
+	index := make(map[string]int)
+	for _, r := range reports {
+		i, ok := index[r.RuleID]
+		if !ok {
+			i = len(groups)
+			index[r.RuleID] = i
+			groups = append(groups, ruleGroup{RuleID: r.RuleID})
+		}
+		groups[i].Reports = append(groups[i].Reports, r)
+	}
+	return groups
+}
+
+// WriteCollapsedText is WriteText with each rule's findings beyond the first
+// few folded into a count, keeping output readable on large contracts
+func (g *GasOptimizer) WriteCollapsedText(w io.Writer) error {
+	if len(g.Reports) == 0 {
+		return g.WriteText(w)
+	}
+	n := 0
+	for _, group := range groupByRule(g.Reports) {
+		total := 0
+		for _, r := range group.Reports {
+			total += r.GasSavings
+		}
+		name := group.RuleID
+		if rule, ok := findRule(group.RuleID); ok {
+			name += " " + rule.Name
+		}
+		noun := "findings"
+		if len(group.Reports) == 1 {
+			noun = "finding"
+		}
+		fmt.Fprintf(w, "== %s: %d %s, %d gas ==\n\n", name, len(group.Reports), noun, total)
+		for i, r := range group.Reports {
+			n++
+			if i == collapseShown {
+				fmt.Fprintf(w, "  ...and %d more\n\n", len(group.Reports)-collapseShown)
+				n += len(group.Reports) - collapseShown - 1
+				break
+			}
+			if err := g.writeReportBlock(w, n, r); err != nil {
+				return err
+			}
+		}
+	}
+	return g.writeSummaryLine(w)
+}
+
+// jsonStream writes a JSON document piece by piece, encoding one value at a
+// time, so a large report list is never held in memory as a whole. Values
+// are indented as json.Encoder with SetIndent(prefix, "  ") would, and the
+// first write error sticks.
+type jsonStream struct {
+	w   *bufio.Writer
+	buf bytes.Buffer
+	enc *json.Encoder
+	err error
+}
+
+func newJSONStream(w io.Writer) *jsonStream {
+	s := &jsonStream{w: bufio.NewWriter(w)}
+	s.enc = json.NewEncoder(&s.buf)
+	s.enc.SetEscapeHTML(false) // keep < and > in source snippets readable
+	return s
+}
+
+// raw writes literal JSON text
+func (s *jsonStream) raw(text string) {
+	if s.err == nil {
+		_, s.err = s.w.WriteString(text)
+	}
+}
+
+// value encodes v at the nesting depth given by prefix
+func (s *jsonStream) value(v any, prefix string) {
+	if s.err != nil {
+		return
+	}
+	s.buf.Reset()
+	s.enc.SetIndent(prefix, "  ")
+	if s.err = s.enc.Encode(v); s.err == nil {
+		_, s.err = s.w.Write(bytes.TrimSuffix(s.buf.Bytes(), []byte("\n")))
+	}
+}
+
+// array writes an array of n elements, encoding element i when it is reached
+func (s *jsonStream) array(prefix string, n int, elem func(i int) any) {
+	if n == 0 {
+		s.raw("[]")
+		return
+	}
+	s.raw("[\n")
+	for i := 0; i < n; i++ {
+		s.raw(prefix + "  ")
+		s.value(elem(i), prefix+"  ")
+		if i < n-1 {
+			s.raw(",")
+		}
+		s.raw("\n")
+	}
+	s.raw(prefix + "]")
+}
+
+// close ends the document with a newline and flushes it
+func (s *jsonStream) close() error {
+	s.raw("\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// jsonReport is a report in JSON output, with its fingerprint
+type jsonReport struct {
+	Report
+	Fingerprint string `json:"fingerprint"`
+}
+
+// WriteJSON writes the reports and metrics as a JSON document with the
+// fields file, reports, suppressed (when findings were beyond
+// --max-reports) and metrics (when collected). Reports are streamed.
+func (g *GasOptimizer) WriteJSON(w io.Writer) error {
+	fps := fingerprints(g.Reports)
+	s := newJSONStream(w)
+	s.raw("{\n  \"file\": ")
+	s.value(g.Path, "  ")
+	s.raw(",\n  \"reports\": ")
+	s.array("  ", len(g.Reports), func(i int) any { return jsonReport{g.Reports[i], fps[i]} })
+	if g.Suppressed > 0 {
+		s.raw(",\n  \"suppressed\": " + strconv.Itoa(g.Suppressed))
+	}
+	summary := g.Summarize()
+	s.raw(",\n  \"byCategory\": ")
+	s.value(summary.ByCategory, "  ")
+	s.raw(",\n  \"byArea\": ")
+	s.value(summary.ByArea, "  ")
+	if len(g.Metrics) > 0 {
+		s.raw(",\n  \"metrics\": ")
+		s.value(g.Metrics, "  ")
+	}
+	if len(g.GasReport) > 0 {
+		s.raw(",\n  \"gasReport\": ")
+		s.value(g.GasReport, "  ")
+	}
+	s.raw("\n}")
+	return s.close()
+}
+
+// SARIF 2.1.0 subset understood by GitHub code scanning and most viewers
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties sarifProperties `json:"properties"`
+
+	// PartialFingerprints lets code scanning track results across runs
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifProperties struct {
+	Category   Category   `json:"category"`
+	Severity   Severity   `json:"severity"`
+	Confidence Confidence `json:"confidence"`
+	GasSavings int        `json:"gasSavings"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           sarifRegion   `json:"region"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+// WriteSARIF writes the reports as a SARIF 2.1.0 log
+func (g *GasOptimizer) WriteSARIF(w io.Writer) error {
+	driver := sarifDriver{Name: "gasoptimizer", Version: Version}
+	for _, r := range Rules {
+		driver.Rules = append(driver.Rules, sarifRule{ID: r.ID, Name: r.Name, ShortDescription: sarifMessage{r.Description}})
+	}
+	fps := fingerprints(g.Reports)
+	results := []sarifResult{}
+	for i, r := range g.Reports {
+		level := "warning"
+		if r.Level == LevelError {
+			level = "error"
+		}
+		region := sarifRegion{StartLine: max(r.line, 1)}
+		if r.Range != nil {
+			region = sarifRegion{StartLine: r.Range.StartLine, StartColumn: r.Range.StartColumn, EndLine: r.Range.EndLine, EndColumn: r.Range.EndColumn}
+		}
+		results = append(results, sarifResult{
+			RuleID:  r.RuleID,
+			Level:   level,
+			Message: sarifMessage{fmt.Sprintf("%s. %s (est. %d gas)", r.Issue, r.Suggestion, r.GasSavings)},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifact{URI: r.file},
+				Region:           region,
+			}}},
+			Properties:          sarifProperties{Category: r.Category, Severity: r.Severity, Confidence: r.Confidence, GasSavings: r.GasSavings},
+			PartialFingerprints: map[string]string{"gasoptimizer/v1": fps[i]},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	})
+}
+
+// JUnit XML as understood by Jenkins, GitLab and most CI test reporters
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the reports as JUnit XML: one testsuite per enabled
+// rule, one failing testcase per finding, and a passing testcase for rules
+// without findings so dashboards show what was checked
+func (g *GasOptimizer) WriteJUnit(w io.Writer) error {
+	byRule := make(map[string][]Report)
+	for _, r := range g.Reports {
+		byRule[r.RuleID] = append(byRule[r.RuleID], r)
+	}
+	doc := junitTestSuites{Name: "gasoptimizer"}
+	for _, rule := range Rules {
+		if g.Config.LevelFor(rule.ID) == LevelOff {
+			continue
+		}
+		suite := junitTestSuite{Name: rule.ID + " " + rule.Name}
+		for _, r := range byRule[rule.ID] {
+			suite.TestCases = append(suite.TestCases, junitTestCase{
+				Name:      r.Issue,
+				ClassName: r.Location,
+				Failure: &junitFailure{
+					Message: r.Issue,
+					Type:    string(r.Severity),
+					Text:    fmt.Sprintf("%s (est. %d gas)", r.Suggestion, r.GasSavings),
+				},
+			})
+		}
+		suite.Failures = len(suite.TestCases)
+		if suite.Failures == 0 {
+			suite.TestCases = []junitTestCase{{Name: rule.Description, ClassName: rule.Name}}
+		}
+		suite.Tests = len(suite.TestCases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+// Code Climate issues as ingested by GitLab Code Quality
+type codeClimateIssue struct {
+	Type        string              `json:"type"`
+	CheckName   string              `json:"check_name"`
+	Description string              `json:"description"`
+	Categories  []string            `json:"categories"`
+	Fingerprint string              `json:"fingerprint"`
+	Severity    string              `json:"severity"`
+	Location    codeClimateLocation `json:"location"`
+}
+
+// codeClimateLocation has either Lines or, for findings with a column
+// range, Positions
+type codeClimateLocation struct {
+	Path      string                `json:"path"`
+	Lines     *codeClimateLines     `json:"lines,omitempty"`
+	Positions *codeClimatePositions `json:"positions,omitempty"`
+}
+
+type codeClimateLines struct {
+	Begin int `json:"begin"`
+	End   int `json:"end"`
+}
+
+type codeClimatePositions struct {
+	Begin codeClimatePosition `json:"begin"`
+	End   codeClimatePosition `json:"end"`
+}
+
+type codeClimatePosition struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// codeClimateSeverities maps severities to Code Climate's scale
+var codeClimateSeverities = map[Severity]string{
+	SeverityInfo: "info", SeverityLow: "minor", SeverityMedium: "major", SeverityHigh: "critical",
+}
+
+// codeClimateCategories maps rule categories to Code Climate's
+var codeClimateCategories = map[Category]string{
+	CategoryGas: "Performance", CategorySafety: "Security", CategoryCorrectness: "Bug Risk",
+}
+
+// WriteCodeClimate writes the reports as a Code Climate issue array. The
+// fingerprint is the report's Fingerprint, so GitLab tracks a finding
+// across runs even when lines are inserted above it.
+func (g *GasOptimizer) WriteCodeClimate(w io.Writer) error {
+	fps := fingerprints(g.Reports)
+	s := newJSONStream(w)
+	s.array("", len(g.Reports), func(i int) any {
+		r := g.Reports[i]
+		begin := max(r.line, 1)
+		end := begin + max(r.endLine-r.startLine, 0)
+		location := codeClimateLocation{Path: r.file, Lines: &codeClimateLines{Begin: begin, End: end}}
+		if r.Range != nil {
+			location = codeClimateLocation{Path: r.file, Positions: &codeClimatePositions{
+				Begin: codeClimatePosition{Line: r.Range.StartLine, Column: r.Range.StartColumn},
+				End:   codeClimatePosition{Line: r.Range.EndLine, Column: r.Range.EndColumn},
+			}}
+		}
+		category := codeClimateCategories[r.Category]
+		if category == "" {
+			category = codeClimateCategories[CategoryGas]
+		}
+		return codeClimateIssue{
+			Type:        "issue",
+			CheckName:   r.RuleID,
+			Description: fmt.Sprintf("%s. %s (est. %d gas)", r.Issue, r.Suggestion, r.GasSavings),
+			Categories:  []string{category},
+			Fingerprint: fps[i],
+			Severity:    codeClimateSeverities[r.Severity],
+			Location:    location,
+		}
+	})
+	return s.close()
+}
+
+// WriteSnapshot writes one "Contract:function(types) savings" line per
+// function, sorted, in the spirit of Foundry's .gas-snapshot, so estimated
+// savings can be diffed across commits. Findings outside function bodies,
+// such as on state variable declarations, are not attributed to a line.
+func (g *GasOptimizer) WriteSnapshot(w io.Writer) error {
+	var lines []string
+	for _, m := range g.Metrics {
+		for _, f := range m.functions {
+			lines = append(lines, fmt.Sprintf("%s:%s %d", m.Contract, f.Signature, f.Savings))
+		}
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportTarget is one --report format:path destination
+type reportTarget struct {
+	Format string
+	Path   string
+}
+
+// reportTargets collects repeatable --report flags
+type reportTargets []reportTarget
+
+func (t *reportTargets) String() string {
+	parts := make([]string, len(*t))
+	for i, target := range *t {
+		parts[i] = target.Format + ":" + target.Path
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t *reportTargets) Set(value string) error {
+	format, path, ok := strings.Cut(value, ":")
+	if !ok || path == "" {
+		return fmt.Errorf("want format:path, got %q", value)
+	}
+	if _, ok := formats[format]; !ok {
+		return fmt.Errorf("unknown format %q (want %s)", format, formatNames())
+	}
+	*t = append(*t, reportTarget{format, path})
+	return nil
+}
+
+// writeReportFile renders one format to a file
+func (g *GasOptimizer) writeReportFile(target reportTarget) error {
+	f, err := os.Create(target.Path)
+	if err != nil {
+		return err
+	}
+	if err := formats[target.Format](g, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// manyReports returns an optimizer holding n distinct findings
+func manyReports(n int) *GasOptimizer {
+	g := &GasOptimizer{Path: "x.sol", Reports: make([]Report, n)}
+	for i := range g.Reports {
+		g.Reports[i] = Report{
+			RuleID:     RuleLoopStorageRead,
+			Severity:   SeverityHigh,
+			Issue:      fmt.Sprintf("Variable 'data[%d]' read 2 times in loop", i),
+			Suggestion: "Cache it in memory before loop",
+			GasSavings: 2100,
+			Location:   fmt.Sprintf("x.sol:%d", i+1),
+			file:       "x.sol",
+			line:       i + 1,
+		}
+	}
+	return g
+}
+
+// benchmarkStreaming reports, besides time and allocations, the live heap
+// per report while the output is written, sampled in an untimed first run.
+// The reports themselves are excluded, so what remains is what the writer
+// keeps, mostly the fingerprints: it stays flat as the report count grows
+// 100x, and well below the bytes of output per report that building the
+// document first would keep.
+func benchmarkStreaming(b *testing.B, write func(*GasOptimizer, io.Writer) error) {
+	for _, n := range []int{1_000, 10_000, 100_000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			g := manyReports(n)
+			var before runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			sampler := &heapSampler{every: 64 << 10}
+			if err := write(g, sampler); err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := write(g, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(sampler.written)/float64(n), "out-B/report")
+			b.ReportMetric(float64(int64(sampler.peak)-int64(before.HeapAlloc))/float64(n), "live-B/report")
+		})
+	}
+}
+
+// heapSampler discards what is written, recording the live heap after
+// every so many bytes
+type heapSampler struct {
+	every, written, next int
+	peak                 uint64
+}
+
+func (s *heapSampler) Write(p []byte) (int, error) {
+	s.written += len(p)
+	if s.written >= s.next {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		s.peak = max(s.peak, m.HeapAlloc)
+		s.next = s.written + s.every
+	}
+	return len(p), nil
+}
+
+func BenchmarkWriteJSON(b *testing.B) {
+	benchmarkStreaming(b, (*GasOptimizer).WriteJSON)
+}
+
+func BenchmarkWriteCodeClimate(b *testing.B) {
+	benchmarkStreaming(b, (*GasOptimizer).WriteCodeClimate)
+}
+
+func TestMultiLineRangeOutputs(t *testing.T) {
+	g := multiLineOptimizer(t)
+
+	var sarif bytes.Buffer
+	if err := g.WriteSARIF(&sarif); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(sarif.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	region := log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region
+	if want := (sarifRegion{StartLine: 3, StartColumn: 17, EndLine: 4, EndColumn: 14}); region != want {
+		t.Errorf("SARIF region = %+v, want %+v", region, want)
+	}
+
+	var cc bytes.Buffer
+	if err := g.WriteCodeClimate(&cc); err != nil {
+		t.Fatal(err)
+	}
+	var issues []codeClimateIssue
+	if err := json.Unmarshal(cc.Bytes(), &issues); err != nil {
+		t.Fatal(err)
+	}
+	want := codeClimatePositions{Begin: codeClimatePosition{Line: 3, Column: 17}, End: codeClimatePosition{Line: 4, Column: 14}}
+	if pos := issues[0].Location.Positions; pos == nil || *pos != want {
+		t.Errorf("Code Climate positions = %+v, want %+v", pos, want)
+	}
+}
+
+func TestWriteTableKeepsReportOrder(t *testing.T) {
+	g := &GasOptimizer{Path: "x.sol"}
+	for i, savings := range []int{10, 500, 30} {
+		g.Reports = append(g.Reports, Report{RuleID: RuleRedundantExpression, Severity: SeverityLow, GasSavings: savings,
+			Issue: fmt.Sprintf("issue %d", i), Location: fmt.Sprintf("x.sol:%d", i+1)})
+	}
+	var out bytes.Buffer
+	if err := g.WriteTable(&out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) < 4 {
+		t.Fatalf("table:\n%s", out.String())
+	}
+	for i, want := range []string{"issue 0", "issue 1", "issue 2"} {
+		if !strings.Contains(lines[i+1], want) {
+			t.Errorf("row %d = %q, want %q", i+1, lines[i+1], want)
+		}
+	}
+}
+
+func TestSnapshotFollowsFilteredReports(t *testing.T) {
+	root, err := decodeSolcAST("x.sol", []byte(loopWithIfJSON(dataIJSON)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &GasOptimizer{Path: "x.sol", Source: strings.Repeat("x", 300), AST: root, Reports: []Report{}}
+	g.Analyze()
+	snapshot := func() string {
+		var out bytes.Buffer
+		if err := g.WriteSnapshot(&out); err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out.String())
+	}
+	if got := snapshot(); got == "C:f() 0" || len(g.Metrics) != 1 || g.Metrics[0].EstimatedOptimizableGas == 0 {
+		t.Fatalf("snapshot before filtering = %q, metrics %+v; want savings", got, g.Metrics)
+	}
+
+	before := snapshot()
+	g.FilterChangedLines([]lineRange{{Start: 1, End: 1}})
+	if got := snapshot(); got != before {
+		t.Errorf("snapshot after keeping every finding = %q, want %q", got, before)
+	}
+	g.FilterChangedLines([]lineRange{{Start: 5, End: 9}}) // the findings are on line 1
+	if got := snapshot(); got != "C:f() 0" {
+		t.Errorf("snapshot after filtering = %q, want C:f() 0", got)
+	}
+	if m := g.Metrics[0]; m.EstimatedOptimizableGas != 0 || m.Score != 100 {
+		t.Errorf("metrics after filtering = %+v, want no savings", m)
+	}
+}
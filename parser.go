@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // TokenType defines types of tokens in Solidity
@@ -15,6 +17,7 @@ const (
 	TokenPunctuation
 	TokenNumber
 	TokenWhitespace
+	TokenString
 )
 
 // Token represents a single token in the Solidity code
@@ -50,7 +53,10 @@ func NewParser(source string) *Parser {
 	}
 }
 
-// tokenize breaks the source code into tokens
+// tokenize breaks the source code into tokens. It never fails: comments are
+// skipped, unterminated strings run to the end of the line and invalid UTF-8
+// is kept as part of identifiers, so every token value is copied verbatim
+// from the source.
 func tokenize(source string) []Token {
 	var tokens []Token
 	lines := strings.Split(source, "\n")
@@ -60,6 +66,7 @@ func tokenize(source string) []Token {
 	}
 	operators := map[string]bool{"=": true, ".": true, ";": true, "<": true, "++": true}
 	punctuation := map[string]bool{"(": true, ")": true, "{": true, "}": true}
+	inComment := false // inside a /* */ comment spanning lines
 
 	for lineNum, line := range lines {
 		line = strings.TrimSpace(line)
@@ -67,40 +74,78 @@ func tokenize(source string) []Token {
 			continue
 		}
 
-		var current string
-		for i := 0; i < len(line); i++ {
-			char := string(line[i])
-
-			if char == " " || char == "\t" {
-				if current != "" {
-					tokens = append(tokens, classifyToken(current, lineNum+1, keywords))
-					current = ""
+		start := -1 // start of the pending identifier or number
+		flush := func(end int) {
+			if start >= 0 {
+				tokens = append(tokens, classifyToken(line[start:end], lineNum+1, keywords))
+				start = -1
+			}
+		}
+		for i := 0; i < len(line); {
+			if inComment {
+				end := strings.Index(line[i:], "*/")
+				if end < 0 {
+					break
 				}
+				i += end + 2
+				inComment = false
 				continue
 			}
 
-			if operators[char] || punctuation[char] {
-				if current != "" {
-					tokens = append(tokens, classifyToken(current, lineNum+1, keywords))
-					current = ""
-				}
+			r, size := utf8.DecodeRuneInString(line[i:])
+			char := line[i : i+size]
+			switch {
+			case strings.HasPrefix(line[i:], "//"):
+				flush(i)
+				i = len(line)
+				continue
+			case strings.HasPrefix(line[i:], "/*"):
+				flush(i)
+				inComment = true
+				i += 2
+				continue
+			case r == '"' || r == '\'':
+				flush(i)
+				end := closingQuote(line, i)
+				tokens = append(tokens, Token{Type: TokenString, Value: line[i:end], Line: lineNum + 1})
+				i = end
+				continue
+			case unicode.IsSpace(r):
+				flush(i)
+			case operators[char] || punctuation[char]:
+				flush(i)
 				tokType := TokenOperator
 				if punctuation[char] {
 					tokType = TokenPunctuation
 				}
 				tokens = append(tokens, Token{Type: tokType, Value: char, Line: lineNum + 1})
-				continue
+			default:
+				if start < 0 {
+					start = i
+				}
 			}
-
-			current += char
-		}
-		if current != "" {
-			tokens = append(tokens, classifyToken(current, lineNum+1, keywords))
+			i += size
 		}
+		flush(len(line))
 	}
 	return tokens
 }
 
+// closingQuote returns the index just past the string literal opening at
+// line[open], or len(line) if it is unterminated
+func closingQuote(line string, open int) int {
+	quote := line[open]
+	for i := open + 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++ // skip the escaped byte
+		case quote:
+			return i + 1
+		}
+	}
+	return len(line)
+}
+
 // classifyToken determines the type of a token
 func classifyToken(value string, line int, keywords map[string]bool) Token {
 	if keywords[value] {
@@ -185,7 +230,13 @@ func (p *Parser) parseLoop(node *Node) *Node {
 		p.advance()
 		body := &Node{Type: "Block", Line: p.Current.Line}
 		for p.Current.Value != "}" && p.Pos < len(p.Tokens) {
-			if p.Current.Type == TokenIdentifier {
+			switch {
+			case p.Current.Type == TokenKeyword && p.Current.Value == "if":
+				if ifNode := p.parseIfStatement(); ifNode != nil {
+					body.Children = append(body.Children, ifNode)
+				}
+				continue
+			case p.Current.Type == TokenIdentifier:
 				if access := p.parseVariableAccess(); access != nil {
 					body.Children = append(body.Children, access)
 				}
@@ -208,9 +259,18 @@ func (p *Parser) parseIfStatement() *Node {
 	}
 	p.advance()
 
+	// Keep reads in the condition; inside a loop they happen every iteration
+	cond := &Node{Type: "Condition", Line: p.Current.Line}
 	for p.Current.Value != ")" && p.Pos < len(p.Tokens) {
+		if p.Current.Type == TokenIdentifier {
+			if access := p.parseVariableAccess(); access != nil {
+				cond.Children = append(cond.Children, access)
+			}
+			continue
+		}
 		p.advance()
 	}
+	ifNode.Children = append(ifNode.Children, cond)
 	p.advance() // Skip ')'
 
 	if p.Current.Type == TokenPunctuation && p.Current.Value == "{" {
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// FuzzTokenize checks that tokenize never panics and never fabricates
+// characters: the token values appear in the input in order
+func FuzzTokenize(f *testing.F) {
+	files, err := filepath.Glob("*.sol")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+	f.Add(`string s = "unterminated`)
+	f.Add("/* open comment\nuint x;")
+	f.Add("uint \xff\xfe x = 'a\\'';")
+	f.Fuzz(func(t *testing.T, source string) {
+		rest := source
+		for _, tok := range tokenize(source) {
+			i := strings.Index(rest, tok.Value)
+			if i < 0 {
+				t.Fatalf("token %q is not in the remaining input %q", tok.Value, rest)
+			}
+			rest = rest[i+len(tok.Value):]
+		}
+	})
+}
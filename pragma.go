@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionConstraint matches one comparator of a version pragma, e.g. ^0.8.0 or <0.9
+var versionConstraint = regexp.MustCompile(`(\^|~|>=|<=|>|<|=)?\s*(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// solcVersion is a major.minor.patch compiler version
+type solcVersion [3]int
+
+// atLeast reports whether v >= other
+func (v solcVersion) atLeast(other solcVersion) bool {
+	for i := range v {
+		if v[i] != other[i] {
+			return v[i] > other[i]
+		}
+	}
+	return true
+}
+
+// pragmaMinVersion returns the lowest compiler version allowed by the
+// source's "pragma solidity" directives. ok is false when there is no
+// pragma or it sets no lower bound.
+func (g *GasOptimizer) pragmaMinVersion(ast *SolcASTNode) (min solcVersion, ok bool) {
+	g.walkSolcAST(ast, func(node *SolcASTNode) {
+		if node.NodeType != "PragmaDirective" || len(node.Literals) == 0 || node.Literals[0] != "solidity" {
+			return
+		}
+		if v, found := minVersion(strings.Join(node.Literals[1:], "")); found && (!ok || v.atLeast(min)) {
+			min, ok = v, true
+		}
+	})
+	return min, ok
+}
+
+// minVersion computes the lower bound of a constraint such as "^0.8.0",
+// ">=0.7.0<0.9.0" or "^0.7.0||^0.8.0": the highest bound within each
+// ||-separated alternative, and the lowest across them. Upper-bound-only
+// constraints, or alternatives, have none.
+func minVersion(constraint string) (solcVersion, bool) {
+	var min solcVersion
+	for i, alternative := range strings.Split(constraint, "||") {
+		v, ok := rangeMinVersion(alternative)
+		if !ok {
+			return solcVersion{}, false
+		}
+		if i == 0 || min.atLeast(v) {
+			min = v
+		}
+	}
+	return min, true
+}
+
+// rangeMinVersion computes the lower bound of a constraint without ||: the
+// highest bound of its comparators
+func rangeMinVersion(constraint string) (solcVersion, bool) {
+	var min solcVersion
+	found := false
+	for _, m := range versionConstraint.FindAllStringSubmatch(constraint, -1) {
+		if m[1] == "<" || m[1] == "<=" {
+			continue
+		}
+		var v solcVersion
+		for i, part := range m[2:] {
+			v[i], _ = strconv.Atoi(part)
+		}
+		if m[1] == ">" {
+			v[2]++
+		}
+		if !found || v.atLeast(min) {
+			min, found = v, true
+		}
+	}
+	return min, found
+}
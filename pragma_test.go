@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestMinVersion(t *testing.T) {
+	tests := []struct {
+		constraint string
+		want       solcVersion
+		ok         bool
+	}{
+		{"^0.8.0", solcVersion{0, 8, 0}, true},
+		{"0.8.19", solcVersion{0, 8, 19}, true},
+		{">=0.7.0<0.9.0", solcVersion{0, 7, 0}, true},
+		{">=0.6.0>=0.7.2", solcVersion{0, 7, 2}, true},
+		{">0.8.3", solcVersion{0, 8, 4}, true},
+		{"<0.9.0", solcVersion{}, false},
+		{"^0.7.0||^0.8.0", solcVersion{0, 7, 0}, true},
+		{"^0.8.0||^0.7.0", solcVersion{0, 7, 0}, true},
+		{">=0.8.4<0.9.0||0.7.6", solcVersion{0, 7, 6}, true},
+		{"^0.8.0||<0.6.0", solcVersion{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := minVersion(tt.constraint)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("minVersion(%q) = %v, %v; want %v, %v", tt.constraint, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+// Rule IDs identify detectors in reports and configuration
+const (
+	RuleLoopStorageRead     = "GAS001"
+	RuleInefficientType     = "GAS002"
+	RuleRedundantExpression = "GAS003"
+	RuleRequireString       = "GAS004"
+	RuleConstantCondition   = "GAS005"
+	RuleLoopAllocation      = "GAS006"
+	RuleMemoryStructParam   = "GAS007"
+	RuleRepeatedIndexAccess = "GAS008"
+	RuleIncrementInIndex    = "GAS009"
+	RuleInlinedModifier     = "GAS010"
+	RuleExternalSelfCall    = "GAS011"
+	RuleToggledBoolFlag     = "GAS012"
+	RuleUnrollableLoop      = "GAS013"
+	RuleManyReturnValues    = "GAS014"
+	RuleSafeMathOnChecked   = "GAS015"
+	RuleNewInLoop           = "GAS016"
+	RuleEncodeWithSignature = "GAS017"
+	RuleRepeatedHashKey     = "GAS018"
+	RuleMissingMutability   = "GAS019"
+	RuleYulRepeatedSload    = "GAS020"
+	RuleYulRedundantMstore  = "GAS021"
+	RuleLoopConstantRead    = "GAS022"
+	RuleRepeatedExternal    = "GAS023"
+	RuleDuplicateZeroCheck  = "GAS024"
+	RuleMissingZeroCheck    = "GAS025"
+	RuleMappingExistence    = "GAS026"
+	RuleLoopStorageWrite    = "GAS027"
+	RuleBoundedLoop         = "GAS028"
+	RuleLargeEventData      = "GAS029"
+	RuleSplitStructWrite    = "GAS030"
+	RuleConversionRoundTrip = "GAS031"
+	RuleUnusedParameter     = "GAS032"
+	RuleRepeatedCodeLength  = "GAS033"
+	RuleModularArithmetic   = "GAS034"
+	RuleConstructorOnly     = "GAS035"
+	RuleRecomputedReturn    = "GAS036"
+	RuleLengthBeforeIndex   = "GAS037"
+	RuleEmitBeforeWrite     = "GAS038"
+	RuleNonNegativeInt      = "GAS039"
+	RuleUnsignedZeroCompare = "GAS040"
+	RuleSharedPreamble      = "GAS041"
+	RuleRepeatedDecode      = "GAS042"
+	RuleGasStipend          = "GAS043"
+	RuleStoragePointer      = "GAS044"
+	RuleUncheckedArithmetic = "GAS045"
+	RuleBlockNumberTiming   = "GAS046"
+	RuleStorageArrayNew     = "GAS047"
+	RuleEmptyLoop           = "GAS048"
+	RuleLoopMappingWrite    = "GAS049"
+	RuleConstructorParam    = "GAS050"
+	RuleBareRevert          = "GAS051"
+	RuleVariableLoopStep    = "GAS052"
+	RuleExpensiveView       = "GAS053"
+	RuleBranchStorageRead   = "GAS054"
+)
+
+// Rule describes a detector
+type Rule struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Category    Category   `json:"category"` // defaults to gas
+	Area        Area       `json:"area"`     // where the cost lies, for summaries
+	Severity    Severity   `json:"severity"`
+	Confidence  Confidence `json:"confidence"` // how likely a finding is a true positive
+	Effort      Effort     `json:"effort"`     // work to apply the fix; defaults to moderate
+	Description string     `json:"description"`
+	Disabled    bool       `json:"disabled,omitempty"` // off unless enabled by config or --enable
+}
+
+// Rules lists every detector in rule ID order
+var Rules = []Rule{
+	{ID: RuleLoopStorageRead, Name: "loop-storage-read", Severity: SeverityHigh, Confidence: ConfidenceHigh,
+		Area: AreaStorage, Description: "Storage variable read repeatedly inside a loop"},
+	{ID: RuleInefficientType, Name: "inefficient-uint-type", Severity: SeverityLow, Confidence: ConfidenceLow,
+		Area: AreaTypes, Effort: EffortTrivial, Description: "Sub-word unsigned integer type outside a packed struct"},
+	{ID: RuleRedundantExpression, Name: "redundant-expression", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaComputation, Description: "Same expression computed more than once in a function"},
+	{ID: RuleRequireString, Name: "require-string-message", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaErrors, Description: "require with a revert string instead of a custom error"},
+	{ID: RuleConstantCondition, Name: "constant-condition", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "if/require on a boolean literal leaves dead code"},
+	{ID: RuleLoopAllocation, Name: "loop-allocation", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaLoops, Description: "Loop-invariant memory allocation repeated every iteration"},
+	{ID: RuleMemoryStructParam, Name: "memory-struct-param", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaTypes, Effort: EffortTrivial, Description: "Read-only memory struct parameter that could be storage or calldata"},
+	{ID: RuleRepeatedIndexAccess, Name: "repeated-index-access", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Description: "Same array or mapping element read three or more times in a function"},
+	{ID: RuleIncrementInIndex, Name: "increment-in-index", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "Increment or decrement folded into an index expression (informational)"},
+	{ID: RuleInlinedModifier, Name: "inlined-modifier", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaDeployment, Description: "Heavy modifier body duplicated into many functions at deployment"},
+	{ID: RuleExternalSelfCall, Name: "external-self-call", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaCalls, Description: "this.f() external call to a function of the same contract"},
+	{ID: RuleToggledBoolFlag, Name: "toggled-bool-flag", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Description: "Frequently toggled bool storage flag that could use the uint256 1/2 pattern"},
+	{ID: RuleUnrollableLoop, Name: "unrollable-loop", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaLoops, Description: "Loop with a small literal trip count that could be unrolled"},
+	{ID: RuleManyReturnValues, Name: "many-return-values", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaTypes, Effort: EffortHigh, Description: "Four or more return values, several sub-word, that could be a struct"},
+	{ID: RuleSafeMathOnChecked, Name: "safemath-on-checked", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "SafeMath call duplicating Solidity 0.8 checked arithmetic"},
+	{ID: RuleNewInLoop, Name: "new-in-loop", Severity: SeverityHigh, Confidence: ConfidenceHigh,
+		Area: AreaLoops, Effort: EffortHigh, Description: "Contract deployed with new inside a loop"},
+	{ID: RuleEncodeWithSignature, Name: "encode-with-signature", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaCalls, Effort: EffortTrivial, Description: "abi.encodeWithSignature hashing a literal signature at runtime"},
+	{ID: RuleRepeatedHashKey, Name: "repeated-hash-key", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Description: "Same keccak256 mapping key computed more than once in a function"},
+	{ID: RuleMissingMutability, Name: "missing-view-pure", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaCalls, Effort: EffortTrivial, Description: "Function that never writes state is not marked view or pure"},
+	{ID: RuleYulRepeatedSload, Name: "yul-repeated-sload", Severity: SeverityHigh, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Description: "Yul sload of the same slot repeated without an intervening sstore"},
+	{ID: RuleYulRedundantMstore, Name: "yul-redundant-mstore", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaComputation, Description: "Yul mstore overwritten before the memory is read"},
+	{ID: RuleLoopConstantRead, Name: "loop-constant-read", Severity: SeverityInfo, Confidence: ConfidenceHigh,
+		Area: AreaLoops, Description: "constant or immutable read inside a loop (informational, no SLOAD involved)"},
+	{ID: RuleRepeatedExternal, Name: "repeated-external-call", Severity: SeverityHigh, Confidence: ConfidenceMedium,
+		Area: AreaCalls, Description: "Same external view call made more than once in a function"},
+	{ID: RuleDuplicateZeroCheck, Name: "duplicate-zero-address-check", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaErrors, Effort: EffortTrivial, Description: "Same address checked against address(0) more than once in a function"},
+	{ID: RuleMissingZeroCheck, Name: "missing-zero-address-check", Severity: SeverityMedium, Confidence: ConfidenceLow,
+		Area: AreaErrors, Category: CategorySafety, Effort: EffortTrivial, Disabled: true,
+		Description: "Address parameter used as a transfer target without an address(0) check (off by default)"},
+	{ID: RuleMappingExistence, Name: "mapping-existence-by-zero", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaStorage, Category: CategoryCorrectness, Description: "Mapping value compared with 0 to test whether a key exists"},
+	{ID: RuleLoopStorageWrite, Name: "loop-storage-array-write", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Description: "Storage array element written on every loop iteration"},
+	{ID: RuleBoundedLoop, Name: "require-bounded-loop", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaLoops, Effort: EffortTrivial, Description: "Loop bounded by a require-capped variable whose increment could be unchecked"},
+	{ID: RuleLargeEventData, Name: "large-event-data", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaEvents, Effort: EffortHigh, Description: "Event emitting non-indexed bytes or string data that a hash might replace"},
+	{ID: RuleSplitStructWrite, Name: "split-struct-write", Severity: SeverityMedium, Confidence: ConfidenceLow,
+		Area: AreaStorage, Description: "Consecutive writes to storage struct fields packed into the same slot"},
+	{ID: RuleConversionRoundTrip, Name: "conversion-round-trip", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaTypes, Effort: EffortTrivial, Description: "string(bytes(x)) or bytes(string(x)) converting a value back to its own type"},
+	{ID: RuleUnusedParameter, Name: "unused-parameter", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaCalls, Effort: EffortTrivial, Description: "Named function parameter never referenced in the body or modifiers"},
+	{ID: RuleRepeatedCodeLength, Name: "repeated-code-length", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaCalls, Description: "Same address checked with .code.length or extcodesize more than once in a function"},
+	{ID: RuleModularArithmetic, Name: "mulmod-addmod", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "(a * b) % n or (a + b) % n that mulmod or addmod computes without overflow"},
+	{ID: RuleConstructorOnly, Name: "constructor-only-write", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "State variable assigned only in the constructor but declared mutable"},
+	{ID: RuleRecomputedReturn, Name: "recomputed-return", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "Function returns an expression it just computed and stored to a state variable"},
+	{ID: RuleLengthBeforeIndex, Name: "length-before-index", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "require(i < arr.length) on a storage array directly followed by arr[i], which checks the length again"},
+	{ID: RuleEmitBeforeWrite, Name: "emit-before-write", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaEvents, Category: CategoryCorrectness, Effort: EffortTrivial, Description: "Event logs a state variable that the function writes afterwards, so it may log a stale value"},
+	{ID: RuleNonNegativeInt, Name: "non-negative-int", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaTypes, Effort: EffortTrivial, Description: "Signed integer only ever assigned and compared with non-negative literals"},
+	{ID: RuleUnsignedZeroCompare, Name: "unsigned-zero-comparison", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaComputation, Category: CategoryCorrectness, Effort: EffortTrivial, Description: "Unsigned value compared with 0 in a way that is always true (x >= 0) or always false (x < 0)"},
+	{ID: RuleSharedPreamble, Name: "shared-require-preamble", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaDeployment, Description: "Public or external functions that start with the same sequence of require checks"},
+	{ID: RuleRepeatedDecode, Name: "repeated-abi-decode", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaCalls, Description: "Same bytes payload decoded with abi.decode more than once in a function"},
+	{ID: RuleGasStipend, Name: "hardcoded-gas-stipend", Severity: SeverityInfo, Confidence: ConfidenceMedium,
+		Area: AreaCalls, Category: CategoryCorrectness, Effort: EffortTrivial, Description: "Call forwarding a literal amount of gas, e.g. addr.call{gas: 2300}(...)"},
+	{ID: RuleStoragePointer, Name: "wasted-storage-pointer", Severity: SeverityLow, Confidence: ConfidenceLow,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "Local storage pointer that is never used, or bypassed by indexing the same element directly"},
+	{ID: RuleUncheckedArithmetic, Name: "unchecked-bounded-arithmetic", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaComputation, Effort: EffortTrivial, Description: "Checked increment or decrement of a local that a require or loop condition already keeps in range"},
+	{ID: RuleBlockNumberTiming, Name: "block-number-timing", Severity: SeverityInfo, Confidence: ConfidenceLow,
+		Area: AreaComputation, Category: CategoryCorrectness, Description: "block.number combined with a literal assuming a block time, e.g. block.number + 7200 for one day"},
+	{ID: RuleStorageArrayNew, Name: "storage-array-new", Severity: SeverityLow, Confidence: ConfidenceHigh,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "Storage array reset by assigning new T[](n) instead of delete"},
+	{ID: RuleEmptyLoop, Name: "empty-loop", Severity: SeverityMedium, Confidence: ConfidenceMedium,
+		Area: AreaLoops, Category: CategoryCorrectness, Effort: EffortTrivial, Description: "Loop whose body is empty or has no side effects"},
+	{ID: RuleLoopMappingWrite, Name: "loop-invariant-mapping-write", Severity: SeverityHigh, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Description: "Mapping entry written on every loop iteration under a key that does not change, e.g. balances[user] += x"},
+	{ID: RuleConstructorParam, Name: "constructor-param-immutable", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "State variable set once from a constructor parameter and never written again"},
+	{ID: RuleBareRevert, Name: "bare-revert", Severity: SeverityInfo, Confidence: ConfidenceHigh,
+		Area: AreaErrors, Effort: EffortTrivial, Description: "revert() or require(false) without a reason in a public or external function"},
+	{ID: RuleVariableLoopStep, Name: "storage-loop-step", Severity: SeverityMedium, Confidence: ConfidenceHigh,
+		Area: AreaLoops, Effort: EffortTrivial, Description: "for loop counter stepped by a state variable the loop never writes, e.g. i += step"},
+	{ID: RuleExpensiveView, Name: "expensive-view", Severity: SeverityInfo, Confidence: ConfidenceMedium,
+		Area: AreaLoops, Effort: EffortHigh, Description: "Public or external view function looping over storage, costly when called on-chain"},
+	{ID: RuleBranchStorageRead, Name: "branch-storage-read", Severity: SeverityLow, Confidence: ConfidenceMedium,
+		Area: AreaStorage, Effort: EffortTrivial, Description: "State variable read in both branches of an if, where one read above the if would do"},
+}
+
+// init defaults rule categories to gas and efforts to moderate
+func init() {
+	for i := range Rules {
+		if Rules[i].Category == "" {
+			Rules[i].Category = CategoryGas
+		}
+		if Rules[i].Effort == "" {
+			Rules[i].Effort = EffortModerate
+		}
+	}
+}
+
+// Category groups rules by what kind of problem they find
+type Category string
+
+const (
+	CategoryGas         Category = "gas"         // costs gas without changing behavior
+	CategorySafety      Category = "safety"      // may lose funds or lock contracts
+	CategoryCorrectness Category = "correctness" // ambiguous or likely unintended logic
+)
+
+// Area groups rules by where the cost they find lies, finer than their
+// category, so savings summaries show where gas debt concentrates
+type Area string
+
+const (
+	AreaStorage     Area = "storage"     // storage reads, writes and layout
+	AreaLoops       Area = "loops"       // work repeated on every iteration
+	AreaTypes       Area = "types"       // choice of types and data locations
+	AreaComputation Area = "computation" // redundant or checked arithmetic and expressions
+	AreaCalls       Area = "calls"       // external calls and ABI encoding
+	AreaErrors      Area = "errors"      // checks and revert data
+	AreaEvents      Area = "events"      // event data
+	AreaDeployment  Area = "deployment"  // bytecode size
+)
+
+// Effort estimates the work of applying a rule's suggestion
+type Effort string
+
+const (
+	EffortTrivial  Effort = "trivial"  // a one-line edit
+	EffortModerate Effort = "moderate" // local restructuring
+	EffortHigh     Effort = "high"     // refactoring across functions or off-chain consumers
+)
+
+// effortWeight divides savings when ranking findings by return on effort
+var effortWeight = map[Effort]int{EffortTrivial: 1, EffortModerate: 3, EffortHigh: 10}
+
+// Severity ranks how much a finding matters
+type Severity string
+
+const (
+	SeverityInfo   Severity = "info"
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+// severityRank orders severities; unknown values rank 0
+var severityRank = map[Severity]int{SeverityInfo: 1, SeverityLow: 2, SeverityMedium: 3, SeverityHigh: 4}
+
+// Confidence ranks how reliable a detector's findings are
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+// confidenceRank orders confidences; unknown values rank 0
+var confidenceRank = map[Confidence]int{ConfidenceLow: 1, ConfidenceMedium: 2, ConfidenceHigh: 3}
+
+// validConfidence reports whether c is a recognized confidence
+func validConfidence(c Confidence) bool {
+	return confidenceRank[c] > 0
+}
+
+// Level decides how a rule's findings affect the run
+type Level string
+
+const (
+	LevelError Level = "error" // printed and fails the run
+	LevelWarn  Level = "warn"  // printed only
+	LevelOff   Level = "off"   // suppressed
+)
+
+// validLevel reports whether l is a recognized level
+func validLevel(l Level) bool {
+	return l == LevelError || l == LevelWarn || l == LevelOff
+}
+
+// findRule looks up a rule by ID
+func findRule(id string) (Rule, bool) {
+	for _, r := range Rules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
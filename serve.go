@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcInternalError  = -32603
+)
+
+// rpcRequest is one JSON-RPC request; requests without an ID are
+// notifications and get no response
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResponse carries either Result or Error
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// analyzeParams are the params of the analyze method: the buffer's URI,
+// echoed back, and its full text
+type analyzeParams struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+// analyzeResult mirrors LSP's PublishDiagnosticsParams
+type analyzeResult struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+// diagnostic mirrors LSP's Diagnostic. Lines are 0-based; characters count
+// bytes, which matches LSP's UTF-16 units for ASCII source.
+type diagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// LSP DiagnosticSeverity values
+const (
+	lspError   = 1
+	lspWarning = 2
+)
+
+// server answers JSON-RPC requests with the analysis of the sent text
+type server struct {
+	cfg  *Config
+	opts Options
+}
+
+// runServe implements "gasoptimizer serve [flags]"
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to JSON config (default "+DefaultConfigFile+" if present)")
+	cacheDir := fs.String("cache-dir", DefaultCacheDir(), "directory caching solc ASTs by source hash")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: gasoptimizer serve [flags]")
+		fmt.Fprintln(fs.Output(), "Reads JSON-RPC requests on stdin and writes responses to stdout.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err == flag.ErrHelp {
+		return ExitOK
+	} else if err != nil {
+		return ExitUsage
+	}
+	cfg, err := loadConfigFlag(*configPath)
+	if err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	s := &server{cfg: cfg, opts: Options{CacheDir: *cacheDir, Logger: DiscardLogger}}
+	if err := s.serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		log.Printf("Error: %v", err)
+		return ExitUsage
+	}
+	return ExitOK
+}
+
+// serve reads a stream of JSON requests from r until EOF, writing one
+// response per line to w. A malformed message cannot be skipped reliably,
+// so it ends the session after a parse error response.
+func (s *server) serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	dec := json.NewDecoder(r)
+	enc := json.NewEncoder(w)
+	for {
+		var req rpcRequest
+		err := dec.Decode(&req)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return enc.Encode(rpcResponse{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+		}
+		result, rpcErr := s.handle(ctx, req)
+		if len(req.ID) == 0 {
+			continue
+		}
+		if err := enc.Encode(rpcResponse{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}); err != nil {
+			return err
+		}
+	}
+}
+
+// handle dispatches one request
+func (s *server) handle(ctx context.Context, req rpcRequest) (any, *rpcError) {
+	if req.Method == "" {
+		return nil, &rpcError{Code: rpcInvalidRequest, Message: "missing method"}
+	}
+	if req.Method != "analyze" {
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	var params analyzeParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+	}
+	g, err := NewGasOptimizerFromReaderOptions(ctx, strings.NewReader(params.Text), s.opts)
+	if err != nil {
+		return nil, &rpcError{Code: rpcInternalError, Message: err.Error()}
+	}
+	g.Config = s.cfg
+	g.Analyze()
+	return analyzeResult{URI: params.URI, Diagnostics: g.diagnostics()}, nil
+}
+
+// diagnostics converts the reports into LSP diagnostics spanning the
+// reported source range, or the whole line when only a line is known
+func (g *GasOptimizer) diagnostics() []diagnostic {
+	sm := newSourceMap(g.Path, g.Source)
+	diags := []diagnostic{}
+	for _, r := range g.Reports {
+		var rng lspRange
+		if start, length, ok := parseSrc(r.Src); ok {
+			rng.Start = lspPositionAt(sm, start)
+			rng.End = lspPositionAt(sm, start+length)
+		} else if r.startLine > 0 {
+			rng.Start = lspPosition{Line: r.startLine - 1}
+			rng.End = lspPosition{Line: r.endLine}
+		}
+		severity := lspWarning
+		if r.Level == LevelError {
+			severity = lspError
+		}
+		diags = append(diags, diagnostic{
+			Range:    rng,
+			Severity: severity,
+			Code:     r.RuleID,
+			Source:   "gasoptimizer",
+			Message:  fmt.Sprintf("%s\n%s (saves ~%d gas)", r.Issue, r.Suggestion, r.GasSavings),
+		})
+	}
+	return diags
+}
+
+// lspPositionAt converts a byte offset into a 0-based LSP position
+func lspPositionAt(sm *sourceMap, offset int) lspPosition {
+	line, col := sm.Position(offset)
+	return lspPosition{Line: line - 1, Character: col - 1}
+}
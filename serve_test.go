@@ -0,0 +1,11 @@
+package main
+
+import "testing"
+
+func TestDiagnosticsMultiLineRange(t *testing.T) {
+	diags := multiLineOptimizer(t).diagnostics()
+	want := lspRange{Start: lspPosition{Line: 2, Character: 16}, End: lspPosition{Line: 3, Character: 13}}
+	if len(diags) != 1 || diags[0].Range != want {
+		t.Fatalf("diagnostics = %+v, want one spanning %+v", diags, want)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fileMarker matches the per-file banners emitted by flattening tools,
+// e.g. "// File: contracts/Token.sol" (truffle-flattener, hardhat).
+var fileMarker = regexp.MustCompile(`^\s*//\s*File:?\s+(\S+\.sol)\b`)
+
+// fileSegment is a run of flattened lines that originated in one file
+type fileSegment struct {
+	File      string
+	StartLine int // first flat line belonging to File
+}
+
+// sourceMap translates offsets in the analyzed (possibly flattened) file
+// back to the original file and line
+type sourceMap struct {
+	path       string
+	source     string
+	lineStarts []int
+	segments   []fileSegment
+}
+
+// newSourceMap indexes line starts and flattening markers in source
+func newSourceMap(path, source string) *sourceMap {
+	m := &sourceMap{path: path, source: source, lineStarts: []int{0}}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			m.lineStarts = append(m.lineStarts, i+1)
+		}
+	}
+	for i, line := range strings.Split(source, "\n") {
+		if match := fileMarker.FindStringSubmatch(line); match != nil {
+			m.segments = append(m.segments, fileSegment{File: match[1], StartLine: i + 2})
+		}
+	}
+	return m
+}
+
+// Position converts a byte offset into a 1-based line and column
+func (m *sourceMap) Position(offset int) (line, col int) {
+	if offset < 0 {
+		offset = 0
+	}
+	idx := sort.Search(len(m.lineStarts), func(i int) bool { return m.lineStarts[i] > offset }) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return idx + 1, offset - m.lineStarts[idx] + 1
+}
+
+// Original maps a flat line to the file and line it was flattened from
+func (m *sourceMap) Original(line int) (string, int) {
+	for i := len(m.segments) - 1; i >= 0; i-- {
+		if seg := m.segments[i]; line >= seg.StartLine {
+			return seg.File, line - seg.StartLine + 1
+		}
+	}
+	return m.path, line
+}
+
+// Resolve turns a report location (a solc "start:length:index" span or a
+// custom parser "line N") into "file:line"
+func (m *sourceMap) Resolve(location string) string {
+	var line int
+	if start, _, ok := parseSrc(location); ok {
+		line, _ = m.Position(start)
+	} else if n, err := strconv.Atoi(strings.TrimPrefix(location, "line ")); err == nil {
+		line = n
+	} else {
+		return location
+	}
+	file, origLine := m.Original(line)
+	return fmt.Sprintf("%s:%d", file, origLine)
+}
+
+// maxSnippet bounds SourceSnippet; longer spans are cut and marked with "..."
+const maxSnippet = 500
+
+// Snippet returns the source between byte offsets start and end, widened to
+// whole lines with context lines on either side when context > 0
+func (m *sourceMap) Snippet(start, end, context int) string {
+	start, end = clamp(start, 0, len(m.source)), clamp(end, 0, len(m.source))
+	if start > end {
+		return ""
+	}
+	if context > 0 {
+		startLine, _ := m.Position(start)
+		endLine, _ := m.Position(max(end-1, start))
+		return m.LineSnippet(startLine, endLine, context)
+	}
+	return truncate(m.source[start:end], maxSnippet)
+}
+
+// LineSnippet returns lines first through last (1-based) with context
+// lines on either side
+func (m *sourceMap) LineSnippet(first, last, context int) string {
+	first = clamp(first-context, 1, len(m.lineStarts))
+	last = clamp(last+context, first, len(m.lineStarts))
+	end := len(m.source)
+	if last < len(m.lineStarts) {
+		end = m.lineStarts[last] - 1 // drop the newline ending the last line
+	}
+	return truncate(m.source[m.lineStarts[first-1]:end], maxSnippet)
+}
+
+// clamp limits n to [lo, hi]
+func clamp(n, lo, hi int) int {
+	return min(max(n, lo), hi)
+}
+
+// truncate cuts s to n bytes, backing up to a UTF-8 boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// parseSrc splits a solc "start:length:index" span
+func parseSrc(src string) (start, length int, ok bool) {
+	parts := strings.Split(src, ":")
+	if len(parts) != 3 {
+		return 0, 0, false
+	}
+	start, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	length, err = strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, length, true
+}
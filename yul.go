@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// YulNode represents a node in solc's Yul AST (--strict-assembly)
+type YulNode struct {
+	NodeType        string    `json:"nodeType"`
+	Src             string    `json:"src"`
+	Name            string    `json:"name,omitempty"`
+	Value           string    `json:"value,omitempty"`
+	Kind            string    `json:"kind,omitempty"`
+	Statements      []YulNode `json:"statements,omitempty"`
+	Body            *YulNode  `json:"body,omitempty"`
+	Expression      *YulNode  `json:"expression,omitempty"`
+	FunctionName    *YulNode  `json:"functionName,omitempty"`
+	Arguments       []YulNode `json:"arguments,omitempty"`
+	ValueExpr       *YulNode  `json:"-"`
+	VariableNames   []YulNode `json:"variableNames,omitempty"`
+	Variables       []YulNode `json:"variables,omitempty"`
+	Condition       *YulNode  `json:"condition,omitempty"`
+	Pre             *YulNode  `json:"pre,omitempty"`
+	Post            *YulNode  `json:"post,omitempty"`
+	Cases           []YulNode `json:"cases,omitempty"`
+	ReturnVariables []YulNode `json:"returnVariables,omitempty"`
+}
+
+// UnmarshalJSON decodes a Yul node. "value" is a string on literals and an
+// expression on declarations and assignments.
+func (n *YulNode) UnmarshalJSON(data []byte) error {
+	type plain YulNode
+	var raw struct {
+		plain
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*n = YulNode(raw.plain)
+	if len(raw.Value) == 0 || string(raw.Value) == "null" {
+		return nil
+	}
+	if raw.Value[0] == '"' {
+		return json.Unmarshal(raw.Value, &n.Value)
+	}
+	n.ValueExpr = &YulNode{}
+	return json.Unmarshal(raw.Value, n.ValueExpr)
+}
+
+// isYulFile reports whether path is a standalone Yul source
+func isYulFile(path string) bool {
+	return strings.HasSuffix(path, ".yul")
+}
+
+// parseYul runs solc in strict assembly mode and decodes the Yul AST. There
+// is no fallback parser for Yul, so a missing or failing solc is an error.
+func parseYul(ctx context.Context, filePath string) (*YulNode, error) {
+	cmd := exec.CommandContext(ctx, "solc", "--strict-assembly", "--ast-compact-json", filePath)
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, &AnalysisError{Kind: ErrSolcNotFound, Path: filePath, Err: err}
+	}
+	if err != nil {
+		return nil, &AnalysisError{Kind: ErrSolcFailed, Path: filePath, Err: err, Detail: string(output)}
+	}
+	// The AST is followed by other output sections, so decode a single
+	// JSON value after the header rather than matching braces
+	header := bytes.Index(output, []byte("AST"))
+	start := -1
+	if header >= 0 {
+		start = bytes.IndexByte(output[header:], '{')
+	}
+	if start < 0 {
+		return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: errors.New("no JSON AST found in solc output"), Detail: string(output)}
+	}
+	var root YulNode
+	if err := json.NewDecoder(bytes.NewReader(output[header+start:])).Decode(&root); err != nil {
+		return nil, &AnalysisError{Kind: ErrASTParse, Path: filePath, Err: err, Detail: string(output)}
+	}
+	return &root, nil
+}
+
+// yulChildren lists a node's children in evaluation order
+func yulChildren(n *YulNode) []*YulNode {
+	var children []*YulNode
+	for _, list := range [][]YulNode{n.Statements, n.Variables, n.VariableNames, n.Cases} {
+		for i := range list {
+			children = append(children, &list[i])
+		}
+	}
+	// Yul evaluates call arguments right to left
+	for i := len(n.Arguments) - 1; i >= 0; i-- {
+		children = append(children, &n.Arguments[i])
+	}
+	for _, child := range [...]*YulNode{n.ValueExpr, n.Expression, n.Pre, n.Condition, n.Body, n.Post} {
+		if child != nil {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+// yulCallName returns the called function of a YulFunctionCall, or ""
+func yulCallName(n *YulNode) string {
+	if n.NodeType != "YulFunctionCall" || n.FunctionName == nil {
+		return ""
+	}
+	return n.FunctionName.Name
+}
+
+// yulKey renders a literal or identifier argument such as a slot or memory
+// offset, or "" for anything computed
+func yulKey(n *YulNode) string {
+	switch n.NodeType {
+	case "YulLiteral":
+		return n.Value
+	case "YulIdentifier":
+		return n.Name
+	}
+	return ""
+}
+
+// yulMemoryReaders are builtins that read memory, making earlier stores live
+var yulMemoryReaders = map[string]bool{
+	"mload": true, "keccak256": true, "return": true, "revert": true, "log0": true, "log1": true,
+	"log2": true, "log3": true, "log4": true, "call": true, "callcode": true, "delegatecall": true,
+	"staticcall": true, "create": true, "create2": true, "mcopy": true, "sha3": true,
+}
+
+// yulBuiltins are EVM opcodes; calls to anything else are user functions
+// whose effects are not tracked
+var yulBuiltins = map[string]bool{
+	"add": true, "sub": true, "mul": true, "div": true, "sdiv": true, "mod": true, "smod": true,
+	"exp": true, "not": true, "lt": true, "gt": true, "slt": true, "sgt": true, "eq": true,
+	"iszero": true, "and": true, "or": true, "xor": true, "byte": true, "shl": true, "shr": true,
+	"sar": true, "addmod": true, "mulmod": true, "signextend": true, "mstore": true, "mstore8": true,
+	"sload": true, "calldataload": true, "calldatasize": true, "callvalue": true, "caller": true,
+	"address": true, "origin": true, "gas": true, "timestamp": true, "number": true, "chainid": true,
+	"pop": true, "tload": true, "msize": true, "returndatasize": true, "codesize": true,
+	"selfbalance": true, "balance": true, "basefee": true, "coinbase": true, "gasprice": true,
+}
+
+// yulScope tracks straight-line state within one block: sload counts per
+// slot and the last unread mstore per offset
+type yulScope struct {
+	sloads  map[string][]*YulNode
+	order   []string
+	mstores map[string]*YulNode
+}
+
+func newYulScope() *yulScope {
+	return &yulScope{sloads: make(map[string][]*YulNode), mstores: make(map[string]*YulNode)}
+}
+
+// analyzeYulAST runs the Yul detectors over every block
+func (g *GasOptimizer) analyzeYulAST(root *YulNode) {
+	g.analyzeYulBlock(root)
+}
+
+// analyzeYulBlock analyzes one block as straight-line code. Nested blocks
+// (if, for, switch, function bodies) start a fresh scope and invalidate the
+// enclosing one, since control flow makes their effects conditional.
+func (g *GasOptimizer) analyzeYulBlock(block *YulNode) {
+	scope := newYulScope()
+	var visit func(n *YulNode)
+	visit = func(n *YulNode) {
+		if n.NodeType == "YulBlock" && n != block {
+			g.flushYulScope(scope)
+			scope = newYulScope()
+			g.analyzeYulBlock(n)
+			return
+		}
+		for _, child := range yulChildren(n) {
+			visit(child)
+		}
+		if n.NodeType == "YulAssignment" {
+			// Reassigned identifiers no longer name the same slot or offset
+			for _, v := range n.VariableNames {
+				g.forgetYulKey(scope, v.Name)
+			}
+			return
+		}
+		if n.NodeType != "YulFunctionCall" {
+			return
+		}
+		name := yulCallName(n)
+		switch {
+		case name == "sload" && len(n.Arguments) == 1:
+			if key := yulKey(&n.Arguments[0]); key != "" {
+				if _, seen := scope.sloads[key]; !seen {
+					scope.order = append(scope.order, key)
+				}
+				scope.sloads[key] = append(scope.sloads[key], n)
+			}
+		case name == "sstore":
+			g.flushYulScope(scope)
+		case name == "mstore" && len(n.Arguments) == 2:
+			key := yulKey(&n.Arguments[0])
+			if key == "" {
+				scope.mstores = make(map[string]*YulNode) // may overlap any offset
+				return
+			}
+			if prev, ok := scope.mstores[key]; ok {
+				g.addReport(Report{
+					RuleID:     RuleYulRedundantMstore,
+					Issue:      fmt.Sprintf("mstore to offset %s is overwritten before memory is read", key),
+					Suggestion: "Remove the first mstore",
+					GasSavings: g.gas.Mstore,
+					Location:   prev.Src,
+				})
+			}
+			scope.mstores[key] = n
+		case yulMemoryReaders[name]:
+			scope.mstores = make(map[string]*YulNode)
+		case !yulBuiltins[name]:
+			// A user function may read memory and write storage
+			g.flushYulScope(scope)
+			scope.mstores = make(map[string]*YulNode)
+		}
+	}
+	visit(block)
+	g.flushYulScope(scope)
+}
+
+// forgetYulKey reports and drops state keyed by a reassigned identifier
+func (g *GasOptimizer) forgetYulKey(scope *yulScope, name string) {
+	g.reportYulSloads(name, scope.sloads[name])
+	delete(scope.sloads, name)
+	delete(scope.mstores, name)
+}
+
+// flushYulScope reports slots loaded more than once in the scope and
+// clears the loads, as after an sstore
+func (g *GasOptimizer) flushYulScope(scope *yulScope) {
+	for _, key := range scope.order {
+		g.reportYulSloads(key, scope.sloads[key])
+		delete(scope.sloads, key) // a forgotten key may be listed twice
+	}
+	scope.sloads, scope.order = make(map[string][]*YulNode), nil
+}
+
+// reportYulSloads reports repeated loads of one slot
+func (g *GasOptimizer) reportYulSloads(key string, loads []*YulNode) {
+	if len(loads) < 2 {
+		return
+	}
+	g.addReport(Report{
+		RuleID:     RuleYulRepeatedSload,
+		Issue:      fmt.Sprintf("sload(%s) is executed %d times without an intervening sstore", key, len(loads)),
+		Suggestion: fmt.Sprintf("Load slot %s once into a let variable", key),
+		GasSavings: (len(loads) - 1) * (g.gas.SloadWarm - g.gas.Mload),
+		Location:   loads[0].Src,
+	})
+}
+
+// walkYul calls fn for n and every node beneath it
+func walkYul(n *YulNode, fn func(*YulNode)) {
+	fn(n)
+	for _, child := range yulChildren(n) {
+		walkYul(child, fn)
+	}
+}